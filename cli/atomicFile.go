@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const lockFile = ".lock"
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path once it is synced to disk. Readers and crashes never see
+// partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename itself survives a crash. Not every
+	// platform supports it, the file is already written either way.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// configLockDepth counts nested withConfigLock calls of this process.
+var configLockDepth int
+
+// withConfigLock runs fn holding advisory lock of the config dir, so writes
+// of concurrent tmxu processes, e.g. autosave and manual save, do not
+// interleave. Nested calls reuse the lock already held.
+func withConfigLock(fn func() error) error {
+	if configLockDepth > 0 {
+		configLockDepth++
+		defer func() { configLockDepth-- }()
+
+		return fn()
+	}
+
+	path, err := getConfigDirPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("Unable to create tmxu config dir: %s \n", path)
+	}
+
+	unlock, err := lockPath(filepath.Join(path, lockFile))
+	if err != nil {
+		return fmt.Errorf("Unable to lock tmxu config dir: %s \n", path)
+	}
+	defer unlock()
+
+	configLockDepth++
+	defer func() { configLockDepth-- }()
+
+	return fn()
+}
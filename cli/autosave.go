@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const defaultAutosaveInterval = 5
+
+// changeSettleDelay is how long autosave -on-change waits after a change
+// before saving, so a burst of changes ends up in a single snapshot.
+const changeSettleDelay = 2 * time.Second
+
+// changeNotifications are control mode notifications about changes which
+// end up in saved sessions. Other changes, like a new working dir of a
+// pane, are only saved every interval.
+var changeNotifications = []string{
+	"sessions-changed", "session-renamed",
+	"window-add", "window-close", "window-renamed",
+	"unlinked-window-add", "unlinked-window-close", "unlinked-window-renamed",
+	"layout-change",
+}
+
+// autosave saves sessions every interval until ctx is done. With onChange
+// sessions are also saved shortly after tmux reports a change. Saves are
+// skipped when the layout did not change since the previous one.
+func autosave(ctx context.Context, interval time.Duration, keep int, scrollback, onChange bool) error {
+	// Start from what is already on disk so restarting the daemon does not
+	// create a duplicate snapshot.
+	last, _ := loadSessionsFile()
+	lastState, _ := sessionsState(last)
+
+	watcher := changeWatcher{enabled: onChange}
+	watcher.connect()
+	defer watcher.disconnect()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		state, err := autosaveOnce(lastState, keep, scrollback)
+		if err != nil {
+			fmt.Printf("[%s] %s", time.Now().Format(time.DateTime), err.Error())
+		} else {
+			lastState = state
+		}
+
+		if !watcher.waitForSave(ctx, ticker) {
+			fmt.Println("Autosave stopped.")
+			return nil
+		}
+	}
+}
+
+// changeWatcher keeps control mode connection of autosave -on-change. While
+// connected, tmux commands of autosave go through the connection as well.
+type changeWatcher struct {
+	enabled bool
+	client  *controlClient
+	backend TmuxRunner
+}
+
+// connect attaches to the server unless it is already connected. Without
+// running server autosave falls back to saving every interval.
+func (w *changeWatcher) connect() {
+	if !w.enabled || w.client != nil {
+		return
+	}
+
+	client, err := newControlClient(activeServer)
+	if err != nil {
+		fmt.Printf("[%s] Unable to watch tmux server for changes, saving every interval \n", time.Now().Format(time.DateTime))
+		return
+	}
+
+	w.client, w.backend = client, runner
+	runner = client
+}
+
+func (w *changeWatcher) disconnect() {
+	if w.client == nil {
+		return
+	}
+
+	w.client.Close()
+	runner, w.client = w.backend, nil
+}
+
+// changes returns notifications of the connection, or nil channel when not
+// connected.
+func (w *changeWatcher) changes() <-chan controlNotification {
+	if w.client == nil {
+		return nil
+	}
+
+	return w.client.Notifications()
+}
+
+// waitForSave blocks until the next save is due: after the interval or once
+// changes reported by tmux settle. Returns false when ctx is done.
+func (w *changeWatcher) waitForSave(ctx context.Context, ticker *time.Ticker) bool {
+	var settle <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			w.connect()
+			return true
+		case <-settle:
+			return true
+		case n, ok := <-w.changes():
+			if !ok {
+				// Session the client was attached to is gone, which is
+				// a change as well. Attach again to any other session.
+				w.disconnect()
+				w.connect()
+				settle = time.After(changeSettleDelay)
+				continue
+			}
+
+			if settle == nil && contains(changeNotifications, n.Name) {
+				settle = time.After(changeSettleDelay)
+			}
+		}
+	}
+}
+
+// autosaveOnce captures sessions and saves them as a timestamped snapshot
+// when they differ from lastState. Returns state of the captured sessions.
+func autosaveOnce(lastState []byte, keep int, scrollback bool) ([]byte, error) {
+	sessions, err := captureSessions()
+	if err != nil {
+		return lastState, err
+	}
+
+	state, err := sessionsState(sessions)
+	if err != nil {
+		return lastState, fmt.Errorf("Cannot marshal session data \n")
+	}
+
+	now := time.Now()
+	if bytes.Equal(state, lastState) {
+		fmt.Printf("[%s] No changes \n", now.Format(time.DateTime))
+		return state, nil
+	}
+
+	name := autoSnapshotName(now)
+	if err := saveSessionsSnapshot(sessions, name, scrollback); err != nil {
+		return lastState, err
+	}
+
+	removed, err := pruneSnapshots(keep)
+	if err != nil {
+		return state, err
+	}
+
+	fmt.Printf("[%s] Snapshot saved: %s (%d removed) \n", now.Format(time.DateTime), name, len(removed))
+	return state, nil
+}
+
+// sessionsState returns comparable representation of sessions layout.
+// Saved scrollback is left out as it changes with every save.
+func sessionsState(sessions []tSession) ([]byte, error) {
+	var layout []tSession
+
+	for _, s := range sessions {
+		s.Windows = append([]tWindow(nil), s.Windows...)
+		for i := range s.Windows {
+			s.Windows[i].Panes = append([]tPane(nil), s.Windows[i].Panes...)
+			for j := range s.Windows[i].Panes {
+				s.Windows[i].Panes[j].Scrollback = ""
+			}
+		}
+
+		layout = append(layout, s)
+	}
+
+	return json.Marshal(layout)
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commandSeparator separates commands run in a single tmux invocation.
+const commandSeparator = ";"
+
+// batchRunner queues tmux commands and runs them in a single invocation of
+// backend, joined with commandSeparator. Queries flush the queue first, so
+// they see effects of the queued commands.
+type batchRunner struct {
+	backend TmuxRunner
+	queue   [][]string
+}
+
+func (b *batchRunner) Run(args ...string) error {
+	b.queue = append(b.queue, args)
+	return nil
+}
+
+func (b *batchRunner) Output(args ...string) ([]byte, error) {
+	if err := b.flush(); err != nil {
+		return nil, err
+	}
+
+	return b.backend.Output(args...)
+}
+
+func (b *batchRunner) Interactive(args ...string) error {
+	if err := b.flush(); err != nil {
+		return err
+	}
+
+	return b.backend.Interactive(args...)
+}
+
+// flush runs queued commands. tmux stops at the first command which fails.
+func (b *batchRunner) flush() error {
+	if len(b.queue) == 0 {
+		return nil
+	}
+
+	var args []string
+	for i, command := range b.queue {
+		if i > 0 {
+			args = append(args, commandSeparator)
+		}
+
+		for _, arg := range command {
+			args = append(args, escapeSeparator(arg))
+		}
+	}
+
+	n := len(b.queue)
+	b.queue = nil
+
+	if err := b.backend.Run(args...); err != nil {
+		return fmt.Errorf("Unable to run batch of %d tmux commands: %s \n", n, err)
+	}
+
+	return nil
+}
+
+// escapeSeparator escapes argument ending with commandSeparator, which tmux
+// would otherwise take as the end of the command.
+func escapeSeparator(arg string) string {
+	if !strings.HasSuffix(arg, commandSeparator) {
+		return arg
+	}
+
+	return strings.TrimSuffix(arg, commandSeparator) + `\` + commandSeparator
+}
+
+// splitCommands splits arguments of batched commands at commandSeparator.
+// Separator escaped at the end of an argument is kept in the argument.
+func splitCommands(args []string) [][]string {
+	commands := [][]string{{}}
+
+	for _, arg := range args {
+		if arg == commandSeparator {
+			commands = append(commands, []string{})
+			continue
+		}
+
+		if s, ok := strings.CutSuffix(arg, `\`+commandSeparator); ok {
+			arg = s + commandSeparator
+		}
+
+		commands[len(commands)-1] = append(commands[len(commands)-1], arg)
+	}
+
+	return commands
+}
+
+// batched runs fn with tmux commands queued and executed in one invocation
+// once fn returns.
+func batched(fn func() error) error {
+	b := &batchRunner{backend: runner}
+
+	runner = b
+	defer func() {
+		runner = b.backend
+	}()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	return b.flush()
+}
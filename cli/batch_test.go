@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBatchedRunsCommandsOnce(t *testing.T) {
+	f := useFakeTmux(t, newTestSession("work", []string{"/tmp"}))
+	f.calls = nil
+
+	err := batched(func() error {
+		if err := runner.Run("rename-window", "-t", "work:1", "code"); err != nil {
+			return err
+		}
+
+		return runner.Run("send-keys", "-t", "work:1.1", "echo a;", "Enter")
+	})
+	if err != nil {
+		t.Fatalf("batched() error = %v", err)
+	}
+
+	want := [][]string{{"rename-window", "-t", "work:1", "code", ";", "send-keys", "-t", "work:1.1", `echo a\;`, "Enter"}}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %q, want %q", f.calls, want)
+	}
+
+	s, _ := f.findSession("work")
+	if keys := s.windows[0].panes[0].keys; !reflect.DeepEqual(keys, []string{"echo a; Enter"}) {
+		t.Errorf("keys = %q, want escaped separator kept in argument", keys)
+	}
+}
+
+func TestBatchedReportsTmuxError(t *testing.T) {
+	useFakeTmux(t, newTestSession("work", []string{"/tmp"}))
+
+	err := batched(func() error {
+		return runner.Run("select-pane", "-t", "missing:1.1", "-T", "x")
+	})
+	if err == nil {
+		t.Fatal("batched() error = nil, want error")
+	}
+
+	if !strings.Contains(err.Error(), "Unable to run batch of 1 tmux commands: fake tmux:") {
+		t.Errorf("batched() error = %q, want message of tmux included", err)
+	}
+}
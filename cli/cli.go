@@ -2,16 +2,28 @@ package cli
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
 var version string
 
+// globalFlags are accepted before the command, e.g. `tmxu --config-dir dir save`.
+var globalFlags = [][]string{
+	{"config-dir", "Dir for config, templates and snapshots. Defaults to $TMXU_HOME or XDG dirs"},
+	{"L", "Socket name of tmux server, like tmux -L. Defaults to socketName from config"},
+	{"S", "Socket path of tmux server, like tmux -S. Defaults to socketPath from config"},
+}
+
 type cli struct {
 	cmds      map[string]Cmd
 	cmdsOrder []string
+	// err is reported by Run when global flags or config file are invalid.
+	err error
 }
 
 func NewCli(v string) *cli {
@@ -27,11 +39,30 @@ func NewCli(v string) *cli {
 	c.newCmd(listSessionsCmd)
 	c.newCmd(saveSessionsCmd)
 	c.newCmd(restoreSessionsCmd)
+	c.newCmd(listSnapshotsCmd)
+	c.newCmd(diffCmd)
+	c.newCmd(autosaveCmd)
 	c.newCmd(listTemplatesCmd)
 	c.newCmd(saveTemplateCmd)
+	c.newCmd(importTemplateCmd)
+	c.newCmd(exportTemplateCmd)
 	c.newCmd(deleteTemplateCmd)
 	c.newCmd(versionCmd)
 
+	c.err = parseGlobalFlags()
+	if c.err == nil {
+		c.err = loadUserConfig()
+	}
+
+	// Server from flags wins over the one from config.
+	if activeServer == (tmuxServer{}) {
+		activeServer = cfg.tmuxServer
+	}
+	if activeServer.SocketPath != "" {
+		activeServer.SocketPath = expandHome(activeServer.SocketPath)
+	}
+	runner = execRunner{server: activeServer}
+
 	return &c
 }
 
@@ -46,6 +77,24 @@ func (c *cli) help(cmdName string) {
 
 	c.listAllCommands()
 
+	fmt.Println("")
+	fmt.Println("Global flags:")
+
+	var d [][]string
+	for _, f := range globalFlags {
+		name := "--" + f[0]
+		if len(f[0]) == 1 {
+			name = "-" + f[0]
+		}
+
+		d = append(d, []string{name, f[1]})
+	}
+	renderTable(d)
+
+	fmt.Println("")
+	fmt.Println("Dirs:")
+	c.listDirs()
+
 	fmt.Println("")
 	fmt.Println("Use `tmxu help [command]` to get detailed information about a specific command.")
 }
@@ -70,6 +119,25 @@ func (c *cli) listAllCommands() {
 	renderTable(d)
 }
 
+// listDirs prints config and state dirs resolved for the current environment,
+// which help of commands refers to.
+func (c *cli) listDirs() {
+	configDir, err := getConfigDirPath()
+	if err != nil {
+		configDir = "unknown"
+	}
+
+	stateDir, err := getStateDirPath()
+	if err != nil {
+		stateDir = "unknown"
+	}
+
+	renderTable([][]string{
+		{"config dir", configDir, "Config, saved sessions and templates"},
+		{"state dir", stateDir, "Snapshots and scrollback"},
+	})
+}
+
 func (c *cli) newCmd(cmd Cmd) {
 	c.cmds[cmd.Command] = cmd
 	c.cmdsOrder = append(c.cmdsOrder, cmd.Command)
@@ -79,7 +147,36 @@ func (c *cli) newCmd(cmd Cmd) {
 	}
 }
 
+// parseGlobalFlags reads global flags given before the command and removes
+// them from os.Args, so commands parse their own flags as before.
+func parseGlobalFlags() error {
+	fs := flag.NewFlagSet("tmxu", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&configDirFlag, "config-dir", "", globalFlags[0][1])
+	fs.StringVar(&activeServer.SocketName, "L", "", globalFlags[1][1])
+	fs.StringVar(&activeServer.SocketPath, "S", "", globalFlags[2][1])
+
+	err := fs.Parse(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		// -h and -help show the general help, like help command.
+		os.Args = []string{os.Args[0], "help"}
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("Unable to read global flags: %s \n", err)
+	}
+
+	os.Args = append([]string{os.Args[0]}, fs.Args()...)
+	return nil
+}
+
 func (c *cli) Run() {
+	if c.err != nil {
+		fmt.Printf("%s", c.err.Error())
+		os.Exit(1)
+	}
+
 	if len(os.Args) < 2 {
 		c.help("")
 		os.Exit(0)
@@ -108,7 +205,13 @@ func (c *cli) Run() {
 	}
 }
 
+// confirm asks prompt and reports whether user agreed. Always agrees when
+// skipConfirm is set in config.
 func confirm(prompt string) bool {
+	if cfg.SkipConfirm {
+		return true
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Printf("%s [y/N]: ", prompt)
 	input, _ := reader.ReadString('\n')
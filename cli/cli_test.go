@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseGlobalFlagsHelp(t *testing.T) {
+	prevArgs := os.Args
+	t.Cleanup(func() { os.Args = prevArgs })
+
+	for _, flag := range []string{"-h", "-help", "--help"} {
+		os.Args = []string{"tmxu", flag}
+
+		if err := parseGlobalFlags(); err != nil {
+			t.Fatalf("parseGlobalFlags(%s) error = %v", flag, err)
+		}
+
+		if want := []string{"tmxu", "help"}; !reflect.DeepEqual(os.Args, want) {
+			t.Errorf("args after %s = %q, want %q", flag, os.Args, want)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 )
 
 type Cmd struct {
@@ -19,6 +25,18 @@ type Cmd struct {
 	Run       func() error
 }
 
+// isFlagSet reports whether flag was passed on the command line.
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
 func (c Cmd) helpLong() {
 	fmt.Printf("%s - %s\n\n", c.Command, c.DescShort)
 
@@ -65,8 +83,7 @@ var listSessionsCmd = Cmd{
 
 		fmt.Println("Available sessions")
 		for i, s := range ls {
-			parts := strings.Split(s, " ")
-			fmt.Printf(" %-3d %-20s\n", i+1, parts[1])
+			fmt.Printf(" %-3d %-20s\n", i+1, s["session_name"])
 		}
 
 		return nil
@@ -167,66 +184,269 @@ var saveSessionsCmd = Cmd{
 	Command:   "save-sessions",
 	Aliases:   []string{"save", "s"},
 	DescShort: "Save tmux sessions",
-	DescLong:  "Captures all running tmux sessions including windows, panes, and layouts. Saves to ~/.config/tmxu/tmux-sessions.json.",
+	DescLong:  "Captures all running tmux sessions including windows, panes, layouts and programs running in panes. Saves to tmux-sessions.json in the config dir and to a snapshot in snapshots/ of the state dir. Without -name a timestamped snapshot is created and only the newest -keep of them are kept. With -scrollback content of panes is stored compressed in scrollback/ of the state dir and printed back on restore. Session names and -include/-exclude glob patterns save only matching sessions to the snapshot and leave tmux-sessions.json as it is.",
+	Arg:       "[sessionName...]",
+	Flags: [][]string{
+		{"name", "Name of the snapshot. Defaults to timestamped snapshot"},
+		{"keep", "Number of timestamped snapshots to keep. 0 keeps all"},
+		{"scrollback", "Save content of every pane to restore it later"},
+		{"include", "Comma separated glob patterns of sessions to save"},
+		{"exclude", "Comma separated glob patterns of sessions to skip"},
+	},
 	Examples: []string{
 		"tmxu save-sessions",
 		"tmxu save",
 		"tmux s",
+		"tmux save -scrollback",
+		"tmux save -name before-upgrade",
+		"tmux save -keep 20",
+		"tmxu save -name work -include 'work-*'",
+		"tmxu save -exclude 'scratch-*'",
 	},
 	Run: func() error {
-		if !confirm("Save all tmux sessions?") {
+		var (
+			snapshotName string
+			keep         int
+			scrollback   bool
+			include      string
+			exclude      string
+		)
+
+		fs := flag.NewFlagSet("save-sessions", flag.ContinueOnError)
+		fs.StringVar(&snapshotName, "name", "", "Name of the snapshot. Defaults to timestamped snapshot")
+		fs.IntVar(&keep, "keep", defaultSnapshotsKeep, "Number of timestamped snapshots to keep. 0 keeps all")
+		fs.BoolVar(&scrollback, "scrollback", false, "Save content of every pane to restore it later")
+		fs.StringVar(&include, "include", "", "Comma separated glob patterns of sessions to save")
+		fs.StringVar(&exclude, "exclude", "", "Comma separated glob patterns of sessions to skip")
+
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			return fmt.Errorf("Unable to read flags for cmd \n")
+		}
+
+		filter, err := newSessionFilter(fs.Args(), include, exclude)
+		if err != nil {
+			return err
+		}
+
+		name := snapshotName
+		if name == "" {
+			name = autoSnapshotName(time.Now())
+		}
+
+		if err := validateSnapshotName(name); err != nil {
+			return err
+		}
+
+		prompt := "Save all tmux sessions?"
+		if !filter.isEmpty() {
+			prompt = "Save selected tmux sessions?"
+		}
+
+		if !confirm(prompt) {
 			fmt.Println("Aborted.")
 			return nil
 		}
 
-		var tSessions []tSession
+		tSessions, err := captureSessions()
+		if err != nil {
+			return err
+		}
 
-		ls, err := ListSessions()
+		tSessions, err = filter.apply(tSessions)
 		if err != nil {
-			return fmt.Errorf("Unable to list all tmux sessions \n")
+			return err
 		}
 
-		for i, s := range ls {
-			ts, err := newTSession(s, i+1)
-			if err != nil {
-				return fmt.Errorf("Unable to create tSession: %s \n", ts.Name)
-			}
+		// Only a save of every session replaces the last save.
+		save := saveSessionsSnapshot
+		if !filter.isEmpty() {
+			save = saveSnapshot
+		}
 
-			lw, err := ListWindows(ts.Name)
-			if err != nil {
-				return fmt.Errorf("Unable to list windows for session: %s \n", ts.Name)
+		if err := save(tSessions, name, scrollback); err != nil {
+			return err
+		}
+
+		if snapshotName == "" {
+			if _, err := pruneSnapshots(keep); err != nil {
+				return err
 			}
+		}
 
-			for _, w := range lw {
-				tw, err := newTWindow(w, ts.Name)
-				if err != nil {
-					return fmt.Errorf("Unable to create tWindow: %s \n", tw.Name)
-				}
+		if filter.isEmpty() {
+			path, _ := getSessionFilePath()
+			fmt.Printf("Tmux sessions saved at %s \n", path)
+		}
+		fmt.Printf("Snapshot saved: %s \n", name)
+		return nil
+	},
+}
 
-				lp, err := ListPanes(tw.SessionWindow)
-				if err != nil {
-					return fmt.Errorf("Unable to list panes for window: %s \n", tw.SessionWindow)
-				}
+var autosaveCmd = Cmd{
+	Command:   "autosave",
+	Aliases:   []string{"daemon"},
+	DescShort: "Save tmux sessions periodically",
+	DescLong:  "Runs in the foreground and saves all tmux sessions every -interval minutes without asking for confirmation. With -on-change sessions are also saved a moment after sessions, windows or layouts change, as reported by tmux over a control mode connection. Nothing is written when sessions did not change since the last save. Every save creates a timestamped snapshot and only the newest -keep of them are kept.",
+	Flags: [][]string{
+		{"interval", "Minutes between saves. Defaults to autosaveInterval from config or 5"},
+		{"keep", "Number of timestamped snapshots to keep. 0 keeps all"},
+		{"scrollback", "Save content of every pane to restore it later"},
+		{"on-change", "Also save when tmux reports a change of sessions, windows or layouts"},
+	},
+	Examples: []string{
+		"tmxu autosave",
+		"tmxu autosave -interval 10 -keep 50",
+		"tmxu autosave -on-change",
+		"tmxu daemon -scrollback",
+	},
+	Run: func() error {
+		var (
+			interval   int
+			keep       int
+			scrollback bool
+			onChange   bool
+		)
 
-				for _, p := range lp {
-					tp, err := newTPane(p, tw.SessionName, tw.SessionWindow)
-					if err != nil {
-						return fmt.Errorf("Unable to create tPane: %s \n", tp.Name)
-					}
+		fs := flag.NewFlagSet("autosave", flag.ContinueOnError)
+		fs.IntVar(&interval, "interval", cfg.AutosaveInterval, "Minutes between saves")
+		fs.IntVar(&keep, "keep", defaultSnapshotsKeep, "Number of timestamped snapshots to keep. 0 keeps all")
+		fs.BoolVar(&scrollback, "scrollback", false, "Save content of every pane to restore it later")
+		fs.BoolVar(&onChange, "on-change", false, "Also save when tmux reports a change of sessions, windows or layouts")
 
-					tw.Panes = append(tw.Panes, tp)
-				}
-				ts.Windows = append(ts.Windows, tw)
-			}
-			tSessions = append(tSessions, ts)
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			return fmt.Errorf("Unable to read flags for cmd \n")
+		}
+
+		if interval <= 0 {
+			return fmt.Errorf("Interval has to be at least 1 minute \n")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if onChange {
+			fmt.Printf("Saving tmux sessions on change and every %d min. Press Ctrl+C to stop \n", interval)
+		} else {
+			fmt.Printf("Saving tmux sessions every %d min. Press Ctrl+C to stop \n", interval)
+		}
+
+		return autosave(ctx, time.Duration(interval)*time.Minute, keep, scrollback, onChange)
+	},
+}
+
+var listSnapshotsCmd = Cmd{
+	Command:   "list-snapshots",
+	Aliases:   []string{"lss"},
+	DescShort: "List all saved snapshots",
+	DescLong:  "Displays all snapshots saved by save-sessions, newest first. Snapshots are stored in snapshots/ of the state dir.",
+	Examples: []string{
+		"tmxu list-snapshots",
+		"tmxu lss",
+	},
+	Run: func() error {
+		snapshots, err := listSnapshotFiles()
+		if err != nil {
+			return err
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Printf("No saved snapshots \n")
+			return nil
+		}
+
+		var d [][]string
+		for _, s := range snapshots {
+			d = append(d, []string{
+				s.Name,
+				fmt.Sprintf("%d sessions", s.Sessions),
+				s.Saved.Format(time.DateTime),
+				fmt.Sprintf("server: %s", s.Server),
+			})
+		}
+
+		renderTable(d)
+		return nil
+	},
+}
+
+var diffCmd = Cmd{
+	Command:   "diff",
+	DescShort: "Compare snapshots or a snapshot with running sessions",
+	DescLong:  "Reports sessions, windows and panes which were added, removed or renamed, windows with a different layout and panes with a different path. Without arguments the last save is compared with running sessions, with one snapshot the snapshot is compared with running sessions.",
+	Arg:       "[snapshot] [snapshot]",
+	Flags: [][]string{
+		{"json", "Print changes as JSON"},
+	},
+	Examples: []string{
+		"tmxu diff",
+		"tmxu diff before-upgrade",
+		"tmxu diff auto-2024-01-01T10-00-00 before-upgrade",
+		"tmxu diff -json before-upgrade",
+	},
+	Run: func() error {
+		var asJSON bool
+
+		fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+		fs.BoolVar(&asJSON, "json", false, "Print changes as JSON")
+
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			return fmt.Errorf("Unable to read flags for cmd \n")
+		}
+
+		if fs.NArg() > 2 {
+			return fmt.Errorf("Expected at most two snapshots \n")
+		}
+
+		var (
+			diff     sessionsDiff
+			from, to []tSession
+			err      error
+		)
+
+		switch fs.NArg() {
+		case 0:
+			diff.From = "last save"
+			from, err = loadSessionsFile()
+		default:
+			diff.From = fs.Arg(0)
+			from, err = loadSnapshotFile(fs.Arg(0))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if fs.NArg() == 2 {
+			diff.To = fs.Arg(1)
+			to, err = loadSnapshotFile(fs.Arg(1))
+		} else {
+			diff.To = "live"
+			to, err = captureSessions()
 		}
 
-		err = saveSessionsFile(tSessions)
 		if err != nil {
-			return fmt.Errorf("Unable to save tmux sessions to file in ~/.config/tmux \n")
+			return err
+		}
+
+		diff.Changes = diffSessions(from, to)
+
+		if asJSON {
+			out, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				return fmt.Errorf("Cannot marshal diff \n")
+			}
+
+			fmt.Println(string(out))
+			return nil
 		}
 
-		fmt.Printf("Tmux sessions saved at ~%s%s \n", configDir, sessionFile)
+		if len(diff.Changes) == 0 {
+			fmt.Printf("No differences between %s and %s \n", diff.From, diff.To)
+			return nil
+		}
+
+		fmt.Printf("Changes from %s to %s: \n", diff.From, diff.To)
+		renderDiff(diff.Changes)
 		return nil
 	},
 }
@@ -235,65 +455,108 @@ var restoreSessionsCmd = Cmd{
 	Command:   "restore-sessions",
 	Aliases:   []string{"restore", "r"},
 	DescShort: "Restore tmux sessions",
-	DescLong:  "Recreates tmux sessions from ~/.config/tmxu/tmux-sessions.json. Skips sessions that already exist.",
+	DescLong:  "Recreates tmux sessions from tmux-sessions.json in the config dir. Skips sessions that already exist. Each session is restored on its own: a session that fails is removed, the rest are still restored and a summary is printed. Programs like vim, less, htop, tail -f or ssh running at save time are started again in their panes. Session names and -include/-exclude glob patterns restore only matching sessions.",
+	Arg:       "[sessionName...]",
 	Flags: [][]string{
-		{"force", "override existing sessions while restoring"},
+		{"force", "override existing sessions while restoring. Same as -on-conflict replace"},
+		{"on-conflict", "What to do with sessions which already exist: skip, replace, rename or merge. Defaults to onConflict from config or skip"},
+		{"from", "Snapshot to restore from. Defaults to the last save"},
+		{"allow", "Comma separated programs to relaunch on top of the defaults"},
+		{"deny", "Comma separated programs never to relaunch"},
+		{"include", "Comma separated glob patterns of sessions to restore"},
+		{"exclude", "Comma separated glob patterns of sessions to skip"},
+		{"dry-run", "Print tmux operations instead of running them"},
 	},
 	Examples: []string{
 		"tmxu restore-sessions",
 		"tmux restore",
 		"tmux r",
 		"tmux restore -force",
+		"tmxu restore -on-conflict merge",
+		"tmux restore -allow k9s,lazygit -deny ssh",
+		"tmux restore -from before-upgrade",
+		"tmxu restore -force -dry-run",
+		"tmxu restore -from before-upgrade api",
+		"tmxu restore -include 'work-*' -exclude work-old",
 	},
 	Run: func() error {
-		var force bool
+		var (
+			force      bool
+			onConflict string
+			from       string
+			allow      string
+			deny       string
+			include    string
+			exclude    string
+			dryRun     bool
+		)
+
 		fs := flag.NewFlagSet("restore-sessions", flag.ContinueOnError)
-		fs.BoolVar(&force, "force", false, "override existing sessions while restoring")
+		fs.BoolVar(&force, "force", false, "override existing sessions while restoring. Same as -on-conflict replace")
+		fs.StringVar(&onConflict, "on-conflict", cfg.OnConflict, "What to do with sessions which already exist: skip, replace, rename or merge")
+		fs.StringVar(&from, "from", "", "Snapshot to restore from. Defaults to the last save")
+		fs.StringVar(&allow, "allow", "", "Comma separated programs to relaunch on top of the defaults")
+		fs.StringVar(&deny, "deny", "", "Comma separated programs never to relaunch")
+		fs.StringVar(&include, "include", "", "Comma separated glob patterns of sessions to restore")
+		fs.StringVar(&exclude, "exclude", "", "Comma separated glob patterns of sessions to skip")
+		fs.BoolVar(&dryRun, "dry-run", false, "Print tmux operations instead of running them")
 
-		if !confirm("Restore tmux sessions from saved file?") {
-			fmt.Println("Aborted.")
-			return nil
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			return fmt.Errorf("Unable to read flags for cmd \n")
 		}
 
-		sessions, err := loadSessionsFile()
+		filter, err := newSessionFilter(fs.Args(), include, exclude)
 		if err != nil {
-			return fmt.Errorf("Unable to load session from session file \n")
+			return err
 		}
 
-		for _, s := range sessions {
-			numberOfPane := 1
+		strategy, err := parseConflictStrategy(onConflict)
+		if err != nil {
+			return err
+		}
 
-			err := NewSession(s, force)
-			if errors.Is(err, errorSessionExists) {
-				fmt.Printf("Session already exist: %s \n", s.Name)
-				continue
-			} else if err != nil {
-				return fmt.Errorf("Unable to create session: %s \n", s.Name)
+		if force {
+			if isFlagSet(fs, "on-conflict") && strategy != conflictReplace {
+				return fmt.Errorf("Use either -force or -on-conflict \n")
 			}
 
-			for _, window := range s.Windows {
-				if err := NewWindow(window); err != nil {
-					return fmt.Errorf("Unable to create window: %s \n", window.SessionWindow)
-				}
+			strategy = conflictReplace
+		}
 
-				for _, pane := range window.Panes {
-					if err := NewPane(pane); err != nil {
-						return fmt.Errorf("Unable to create pane: %s \n", pane.Name)
-					}
-				}
+		if !dryRun && !confirm("Restore tmux sessions from saved file?") {
+			fmt.Println("Aborted.")
+			return nil
+		}
 
-				err := SetWindowLayout(window)
-				if err != nil {
-					return fmt.Errorf("Unable to set layout for window: %s \n", window.SessionWindow)
-				}
+		var data sessionsFile
+		if from != "" {
+			data, err = readSnapshotFile(from)
+		} else {
+			data, err = readSessionsFile()
+		}
 
-				numberOfPane += len(window.Panes)
-			}
+		if err != nil {
+			return err
+		}
 
-			fmt.Printf("Session created: %s (%d windows) [%d panes] \n", s.Name, len(s.Windows), numberOfPane)
+		if data.tmuxServer != activeServer {
+			fmt.Printf("Sessions were saved from %s server, restoring to %s server \n", data.tmuxServer, activeServer)
 		}
 
-		return nil
+		sessions, err := filter.apply(data.Sessions)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			defer startDryRun()()
+		}
+
+		return restoreSessions(sessions, restoreOptions{
+			onConflict: strategy,
+			commands:   newCommandPolicy(allow, deny),
+			dryRun:     dryRun,
+		})
 	},
 }
 
@@ -301,7 +564,7 @@ var listTemplatesCmd = Cmd{
 	Command:   "list-templates",
 	Aliases:   []string{"lt"},
 	DescShort: "List all saved templates",
-	DescLong:  "Displays all saved templates with their windows and panes. Templates are stored in ~/.config/tmxu/templates/.",
+	DescLong:  "Displays all saved templates with their windows and panes. Templates are stored in templates/ of the config dir.",
 	Examples: []string{
 		"tmxu list-templates",
 		"tmxu lt",
@@ -309,7 +572,7 @@ var listTemplatesCmd = Cmd{
 	Run: func() error {
 		ts, err := loadTemplateFiles()
 		if err != nil {
-			return fmt.Errorf("Unable to list availabe templates in `~/.config/tmxu/templates` \n")
+			return err
 		}
 
 		if len(ts) == 0 {
@@ -335,21 +598,31 @@ var saveTemplateCmd = Cmd{
 	Command:   "save-template",
 	Aliases:   []string{"st"},
 	DescShort: "Save session as template",
-	DescLong:  "Saves a running tmux session as a reusable template. Templates are stored in ~/.config/tmxu/templates/.",
+	DescLong:  "Saves a running tmux session as a reusable template. Pane paths are saved relative to -root, so new-session -path can place them in another project. Templates are stored in templates/ of the config dir as JSON, YAML or TOML.",
 	Arg:       "[sessionName]",
 	Flags: [][]string{
 		{"name", "Name of the template. Defaults to session name"},
+		{"root", "Project root pane paths are saved relative to. Defaults to path of the first pane"},
+		{"format", "File format of the template: json, yaml or toml. Defaults to json"},
 	},
 	Examples: []string{
 		"tmxu st sessionName",
 		"tmxu save-template sessionName",
 		"tmxu save-template -name templateName sessionName",
+		"tmxu save-template -root ~/projects/app sessionName",
+		"tmxu save-template -format yaml sessionName",
 	},
 	Run: func() error {
-		var templateName string
+		var (
+			templateName string
+			root         string
+			formatName   string
+		)
 
 		fs := flag.NewFlagSet("new-session", flag.ContinueOnError)
 		fs.StringVar(&templateName, "name", "", "Name of the template. Default to session name")
+		fs.StringVar(&root, "root", "", "Project root pane paths are saved relative to. Defaults to path of the first pane")
+		fs.StringVar(&formatName, "format", string(formatJSON), "File format of the template: json, yaml or toml")
 
 		if err := fs.Parse(os.Args[2:]); err != nil {
 			return fmt.Errorf("Unable to read flags for cmd \n")
@@ -360,60 +633,227 @@ var saveTemplateCmd = Cmd{
 			return fmt.Errorf("No session name provided. Provide tmux session name you want save as template. \n")
 		}
 
+		format, err := parseFileFormat(formatName)
+		if err != nil {
+			return err
+		}
+
 		hs, err := HasSession(sessionName)
 		if err != nil || !hs {
 			return fmt.Errorf("Unable to check session: %s \n", sessionName)
 		}
 
-		const TEMP_VALUE = "TEMP_VALUE"
 		ts := tSession{
 			Name: sessionName,
 		}
 
-		lw, err := ListWindows(ts.Name)
+		procs, _ := listProcesses()
+		ts, err = captureSession(ts, procs)
 		if err != nil {
-			return fmt.Errorf("Unable to list windows for session: %s \n", ts.Name)
+			return err
 		}
 
-		for _, w := range lw {
-			tw, err := newTWindow(w, ts.Name)
-			if err != nil {
-				return fmt.Errorf("Unable to create tWindow: %s \n", tw.Name)
-			}
+		if root == "" && len(ts.Windows) > 0 && len(ts.Windows[0].Panes) > 0 {
+			root = ts.Windows[0].Panes[0].Path
+		}
 
-			lp, err := ListPanes(tw.SessionWindow)
-			if err != nil {
-				return fmt.Errorf("Unable to list panes for window: %s \n", tw.SessionWindow)
-			}
+		root, err = filepath.Abs(root)
+		if err != nil {
+			return fmt.Errorf("Unable to resolve project root: %s \n", root)
+		}
 
-			for _, p := range lp {
-				tp, err := newTPane(p, ts.Name, tw.SessionWindow)
-				if err != nil {
-					return fmt.Errorf("Unable to create tPane: %s \n", tp.Name)
-				}
+		for i := range ts.Windows {
+			sessionWindow := fmt.Sprintf("%s:%d", namePlaceholder, ts.Windows[i].Order)
 
-				tp.SessionName = TEMP_VALUE
-				tp.SessionWindow = TEMP_VALUE
-				tp.Path = TEMP_VALUE
-				tw.Panes = append(tw.Panes, tp)
+			for j := range ts.Windows[i].Panes {
+				pane := &ts.Windows[i].Panes[j]
+				pane.SessionName = namePlaceholder
+				pane.SessionWindow = sessionWindow
+				pane.Path = relativePanePath(pane.Path, root)
 			}
 
-			tw.SessionName = TEMP_VALUE
-			tw.SessionWindow = TEMP_VALUE
-			ts.Windows = append(ts.Windows, tw)
+			ts.Windows[i].SessionName = namePlaceholder
+			ts.Windows[i].SessionWindow = sessionWindow
 		}
 
 		if templateName == "" {
 			templateName = sessionName
 		}
 
+		// Titles and window names set by programs are not placeholders.
+		ts = literalTemplate(ts)
 		ts.Name = templateName
-		err = saveTemplateFile(tTemplate(ts))
+		filePath, err := saveTemplateFile(tTemplate(ts), format)
 		if err != nil {
 			return fmt.Errorf("Unable to save session: %s as template \n", sessionName)
 		}
 
-		fmt.Printf("Templates saved at: ~/.config/tmxu/templates/%s.json \n", ts.Name)
+		fmt.Printf("Templates saved at: %s \n", filePath)
+		return nil
+	},
+}
+
+var importTemplateCmd = Cmd{
+	Command:   "import-template",
+	Aliases:   []string{"it"},
+	DescShort: "Import tmuxinator or tmuxp project as template",
+	DescLong:  "Converts tmuxinator project or tmuxp workspace file into a template stored in templates/ of the config dir. Windows, panes, layouts, root directories and commands are imported, keys tmxu does not support are listed.",
+	Arg:       "[file]",
+	Flags: [][]string{
+		{"name", "Name of the template. Defaults to name of the project"},
+		{"from", "Kind of the project file: tmuxinator or tmuxp. Detected when not set"},
+		{"format", "File format of the template: json, yaml or toml. Defaults to json"},
+	},
+	Examples: []string{
+		"tmxu import-template ~/.config/tmuxinator/api.yml",
+		"tmxu it -from tmuxp -name api ~/.tmuxp/api.yaml",
+		"tmxu it -format yaml ~/.config/tmuxinator/api.yml",
+	},
+	Run: func() error {
+		var (
+			templateName string
+			kind         string
+			formatName   string
+		)
+
+		fs := flag.NewFlagSet("import-template", flag.ContinueOnError)
+		fs.StringVar(&templateName, "name", "", "Name of the template. Defaults to name of the project")
+		fs.StringVar(&kind, "from", "", "Kind of the project file: tmuxinator or tmuxp")
+		fs.StringVar(&formatName, "format", string(formatJSON), "File format of the template: json, yaml or toml")
+
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			return fmt.Errorf("Unable to read flags for cmd \n")
+		}
+
+		filePath := fs.Arg(0)
+		if filePath == "" {
+			return fmt.Errorf("No file provided. Provide tmuxinator or tmuxp file you want to import \n")
+		}
+
+		format, err := parseFileFormat(formatName)
+		if err != nil {
+			return err
+		}
+
+		t, report, err := importTemplateFile(expandHome(filePath), kind)
+		if err != nil {
+			return err
+		}
+
+		if templateName != "" {
+			t.Name = templateName
+		}
+
+		if t.Name == "" {
+			return fmt.Errorf("Project has no name. Provide template name with -name \n")
+		}
+
+		templatePath, err := saveTemplateFile(t, format)
+		if err != nil {
+			return fmt.Errorf("Unable to save template: %s \n", t.Name)
+		}
+
+		if len(report.unsupported) > 0 {
+			fmt.Println("Unsupported keys were skipped:")
+			for _, k := range report.unsupported {
+				fmt.Printf("  %s \n", k)
+			}
+		}
+
+		fmt.Printf("Template saved at: %s \n", templatePath)
+		return nil
+	},
+}
+
+var exportTemplateCmd = Cmd{
+	Command:   "export-template",
+	Aliases:   []string{"et"},
+	DescShort: "Export template for tmuxinator, tmuxp or as shell script",
+	DescLong:  "Renders a saved template as tmuxinator project, tmuxp workspace or standalone bash script of tmux commands, so it can be used without tmxu. Placeholders are expanded like in new-session. Parts of the template the target cannot express are listed.",
+	Arg:       "[templateName]",
+	Flags: [][]string{
+		{"to", "Target format: tmuxinator, tmuxp or sh. Defaults to sh"},
+		{"o", "Output file. Defaults to standard output"},
+		{"path", "Project root of exported session. Defaults to root of the template"},
+		{"var", "Template variable as key=value. Can be repeated"},
+	},
+	Examples: []string{
+		"tmxu export-template templateName",
+		"tmxu et -to tmuxinator -o ~/.config/tmuxinator/api.yml templateName",
+		"tmxu et -to tmuxp -path ~/projects/api templateName",
+	},
+	Run: func() error {
+		var (
+			kind   string
+			output string
+			root   string
+		)
+
+		fs := flag.NewFlagSet("export-template", flag.ContinueOnError)
+		fs.StringVar(&kind, "to", exportShell, "Target format: tmuxinator, tmuxp or sh")
+		fs.StringVar(&output, "o", "", "Output file. Defaults to standard output")
+		fs.StringVar(&root, "path", "", "Project root of exported session. Defaults to root of the template")
+		vars := make(varsFlag)
+		fs.Var(vars, "var", "Template variable as key=value. Can be repeated")
+
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			return fmt.Errorf("Unable to read flags for cmd \n")
+		}
+
+		templateName := fs.Arg(0)
+		if templateName == "" {
+			return fmt.Errorf("No template name provided. Provide template name you want to export \n")
+		}
+
+		t, err := loadTemplateFile(templateName)
+		if err != nil {
+			return err
+		}
+
+		if root == "" {
+			root = t.Root
+		}
+
+		rootVar := root
+		if rootVar == "" {
+			rootVar = "."
+		}
+
+		data, err := newTemplateVars(rootVar, t.Name, vars)
+		if err != nil {
+			return err
+		}
+
+		t, err = expandTemplate(t, data)
+		if err != nil {
+			return err
+		}
+
+		t.Root = root
+		out, warnings, err := exportTemplate(t, kind)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "Skipped: %s \n", w)
+		}
+
+		if output == "" {
+			fmt.Print(string(out))
+			return nil
+		}
+
+		perm := os.FileMode(0644)
+		if kind == exportShell {
+			perm = 0755
+		}
+
+		if err := os.WriteFile(output, out, perm); err != nil {
+			return fmt.Errorf("Cannot save exported template at path: %s \n", output)
+		}
+
+		fmt.Printf("Template exported to: %s \n", output)
 		return nil
 	},
 }
@@ -422,7 +862,7 @@ var deleteTemplateCmd = Cmd{
 	Command:   "delete-template",
 	Aliases:   []string{"dt"},
 	DescShort: "Delete saved template",
-	DescLong:  "Removes a template file from ~/.config/tmxu/templates/.",
+	DescLong:  "Removes a template file from templates/ of the config dir.",
 	Arg:       "[templateName]",
 	Examples: []string{
 		"tmxu delete-template templateName",
@@ -435,10 +875,10 @@ var deleteTemplateCmd = Cmd{
 
 		templateName := os.Args[2]
 		if err := deleteTemplateFile(templateName); err != nil {
-			return fmt.Errorf("Unable to delete template: `~/.config/tmxu/templates/%s.json` \n", templateName)
+			return fmt.Errorf("Unable to delete template: %s \n", templateName)
 		}
 
-		fmt.Printf("Template deleted: `~/.config/tmxu/templates/%s.json` \n", templateName)
+		fmt.Printf("Template deleted: %s \n", templateName)
 		return nil
 	},
 }
@@ -447,11 +887,13 @@ var newSessionCmd = Cmd{
 	Command:   "new-session",
 	Aliases:   []string{"new", "ns"},
 	DescShort: "Create new session base on the template",
-	DescLong:  "Creates a new tmux session, optionally based on a saved template.",
+	DescLong:  "Creates a new tmux session, optionally based on a saved template. Placeholders in window names, pane titles, paths and commands of the template are expanded: {{.Root}} with -path, {{.Name}} with session name, {{.Env.X}} with environment variable X and {{.key}} with -var key=value.",
 	Arg:       "[sessionName]",
 	Flags: [][]string{
-		{"path", "Initial path for all panes. Defaults to current directory"},
-		{"templ", "Template to create new session based on"},
+		{"path", "Project root relative pane paths are resolved against. Defaults to root of the template or current directory"},
+		{"templ", "Template to create new session based on. Defaults to defaultTemplate from config"},
+		{"var", "Template variable as key=value. Can be repeated"},
+		{"dry-run", "Print tmux operations and hooks instead of running them"},
 	},
 	Examples: []string{
 		"tmxu new sessionName",
@@ -459,6 +901,8 @@ var newSessionCmd = Cmd{
 		"tmxu new-session sessionName",
 		"tmxu new-session -templ templateName sessionName",
 		"tmxu new-session -path /tmp/app -templ templateName sessionName",
+		"tmxu new-session -templ templateName -var port=8080 -var env=dev sessionName",
+		"tmxu new-session -templ templateName -dry-run sessionName",
 	},
 	Run: func() error {
 		pwd, err := os.Getwd()
@@ -467,18 +911,26 @@ var newSessionCmd = Cmd{
 		}
 
 		var (
-			path  string
-			templ string
+			path   string
+			templ  string
+			dryRun bool
 		)
 
 		fs := flag.NewFlagSet("new-session", flag.ContinueOnError)
-		fs.StringVar(&path, "path", pwd, "Initial path for all panes. Default to pwd")
-		fs.StringVar(&templ, "templ", "", "Template to create new session base on")
+		fs.StringVar(&path, "path", pwd, "Project root relative pane paths are resolved against. Default to pwd")
+		fs.StringVar(&templ, "templ", cfg.DefaultTemplate, "Template to create new session base on")
+		vars := make(varsFlag)
+		fs.Var(vars, "var", "Template variable as key=value. Can be repeated")
+		fs.BoolVar(&dryRun, "dry-run", false, "Print tmux operations and hooks instead of running them")
 
 		if err := fs.Parse(os.Args[2:]); err != nil {
 			return fmt.Errorf("Unable to read cmd options \n")
 		}
 
+		if dryRun {
+			defer startDryRun()()
+		}
+
 		sessionName := fs.Arg(0)
 		if templ == "" {
 			t := tSession{
@@ -496,35 +948,25 @@ var newSessionCmd = Cmd{
 		}
 
 		t, err := loadTemplateFile(templ)
-		t.Name = sessionName
 		if err != nil {
-			return fmt.Errorf("Unable to read template file: %s \n", sessionName)
+			return err
 		}
 
-		err = NewSession(t, false)
-		if errors.Is(err, errorSessionExists) {
-			return fmt.Errorf("Session already exist: %s \n", t.Name)
-		} else if err != nil {
-			return fmt.Errorf("Unable to create session: %s \n", t.Name)
+		if t.Root != "" && !isFlagSet(fs, "path") {
+			path = expandHome(t.Root)
 		}
 
-		for i, window := range t.Windows {
-			window.SessionName = sessionName
-			window.SessionWindow = fmt.Sprintf("%s:%d", sessionName, i+1)
-
-			if err := NewWindow(window); err != nil {
-				return fmt.Errorf("Unable to create window: %s \n", window.SessionWindow)
-			}
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("Unable to resolve path: %s \n", path)
+		}
 
-			for _, pane := range window.Panes {
-				pane.Path = path
-				pane.SessionName = sessionName
-				pane.SessionWindow = window.SessionWindow
+		if err := newSessionFromTemplate(t, sessionName, path, vars); err != nil {
+			return err
+		}
 
-				if err := NewPane(pane); err != nil {
-					return fmt.Errorf("Unable to create pane: %s \n", pane.Name)
-				}
-			}
+		if dryRun {
+			return nil
 		}
 
 		fmt.Printf("Session: %s created! \nRun `tmxu attach %s` in order to use newly created session \n", sessionName, sessionName)
@@ -7,23 +7,22 @@ import (
 	"path/filepath"
 )
 
-const configDir = "/.config/tmxu/"
 const templatesDir = "templates"
 const sessionFile = "tmux-sessions.json"
 
 func saveSessionsFile(data []tSession) error {
 	hasConfigDir, err := hasConfigDir()
 	if err != nil {
-		return fmt.Errorf("Cannot check for config dir at path: ~%s \n", configDir)
+		return fmt.Errorf("Cannot check for config dir \n")
 	}
 
 	if !hasConfigDir {
 		if err := createConfigDir(); err != nil {
-			return fmt.Errorf("Cannot create config dir: ~%s \n", configDir)
+			return err
 		}
 	}
 
-	j, err := json.MarshalIndent(data, "", "  ")
+	j, err := json.MarshalIndent(sessionsFile{SchemaVersion: schemaVersion, tmuxServer: activeServer, Sessions: data}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("Cannot marshal sassion data \n")
 	}
@@ -33,7 +32,9 @@ func saveSessionsFile(data []tSession) error {
 		return fmt.Errorf("Unable to get file path \n")
 	}
 
-	err = os.WriteFile(path, j, 0644)
+	err = withConfigLock(func() error {
+		return writeFileAtomic(path, j, 0644)
+	})
 	if err != nil {
 		return fmt.Errorf("Cannot save session file at path: %s \n", path)
 	}
@@ -42,13 +43,11 @@ func saveSessionsFile(data []tSession) error {
 }
 
 func hasConfigDir() (bool, error) {
-	homeDir, err := os.UserHomeDir()
+	path, err := getConfigDirPath()
 	if err != nil {
-		return false, fmt.Errorf("Unable to get home dir \n")
+		return false, err
 	}
 
-	path := filepath.Join(homeDir, configDir)
-
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return false, nil
 	} else {
@@ -57,12 +56,11 @@ func hasConfigDir() (bool, error) {
 }
 
 func createConfigDir() error {
-	homeDir, err := os.UserHomeDir()
+	path, err := getConfigDirPath()
 	if err != nil {
-		return fmt.Errorf("Unable to get home dir \n")
+		return err
 	}
 
-	path := filepath.Join(homeDir, configDir)
 	if err = os.MkdirAll(path, 0755); err != nil {
 		return fmt.Errorf("Unable to create tmxu config dir: %s ]n", path)
 	}
@@ -71,42 +69,39 @@ func createConfigDir() error {
 }
 
 func loadSessionsFile() ([]tSession, error) {
-	var data []tSession
+	data, err := readSessionsFile()
+	return data.Sessions, err
+}
 
-	path, err := getSessionFilePath()
-	if err != nil {
-		return nil, fmt.Errorf("Unable to get file path \n")
-	}
+// readSessionsFile returns sessions file together with the server the
+// sessions were saved from.
+func readSessionsFile() (sessionsFile, error) {
+	var data sessionsFile
 
-	out, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to read tmux session file at path: %s \n", path)
-	}
-
-	err = json.Unmarshal(out, &data)
+	path, err := getSessionFilePath()
 	if err != nil {
-		return nil, fmt.Errorf("Cannot marshal sassion data \n")
+		return data, fmt.Errorf("Unable to get file path \n")
 	}
 
-	return data, nil
+	err = readVersionedFile(path, formatJSON, sessionsMigrations, &data)
+	return data, err
 }
 
 func getSessionFilePath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	path, err := getConfigDirPath()
 	if err != nil {
-		return "", fmt.Errorf("Unable to get home dir \n")
+		return "", err
 	}
 
-	return filepath.Join(homeDir, configDir, sessionFile), nil
+	return filepath.Join(path, sessionFile), nil
 }
 
 func hasTemplatesDir() (bool, error) {
-	_, err := os.UserHomeDir()
+	path, err := getTemplatesDirPath()
 	if err != nil {
-		return false, fmt.Errorf("Unable to get home dir \n")
+		return false, err
 	}
 
-	path, _ := getTemplatesDirPath()
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return false, nil
 	} else {
@@ -115,12 +110,11 @@ func hasTemplatesDir() (bool, error) {
 }
 
 func createTemplatesDir() error {
-	_, err := os.UserHomeDir()
+	path, err := getTemplatesDirPath()
 	if err != nil {
-		return fmt.Errorf("Unable to get home dir \n")
+		return err
 	}
 
-	path, _ := getTemplatesDirPath()
 	if err = os.MkdirAll(path, 0755); err != nil {
 		return fmt.Errorf("Unable to create tmxu templates dir: %s \n", path)
 	}
@@ -129,32 +123,77 @@ func createTemplatesDir() error {
 }
 
 func getTemplatesDirPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	path, err := getConfigDirPath()
 	if err != nil {
 		return "", fmt.Errorf("Unable to get templates dir \n")
 	}
 
-	return filepath.Join(homeDir, configDir, templatesDir), nil
+	return filepath.Join(path, templatesDir), nil
 }
 
-func loadTemplateFile(templateName string) (tTemplate, error) {
+// findTemplateFile returns path and format of template file with given name
+// in any of the supported formats.
+func findTemplateFile(templateName string) (string, fileFormat, error) {
 	path, err := getTemplatesDirPath()
 	if err != nil {
-		return tTemplate{}, fmt.Errorf("Cannot read templates dir \n")
+		return "", "", fmt.Errorf("Cannot read templates dir \n")
 	}
 
-	filePath := fmt.Sprintf("%s/%s.json", path, templateName)
-	out, err := os.ReadFile(filePath)
-	if err != nil {
-		return tTemplate{}, fmt.Errorf("Unable to read tmux template file at path: %s \n", filePath)
+	for _, ext := range templateExtensions {
+		filePath := filepath.Join(path, templateName+ext)
+		if _, err := os.Stat(filePath); err == nil {
+			format, _ := formatFromPath(filePath)
+			return filePath, format, nil
+		}
 	}
 
-	var t tTemplate
-	err = json.Unmarshal(out, &t)
+	return "", "", fmt.Errorf("Unable to find template: %s \n", templateName)
+}
+
+func loadTemplateFile(templateName string) (tTemplate, error) {
+	filePath, format, err := findTemplateFile(templateName)
 	if err != nil {
-		return tTemplate{}, fmt.Errorf("Cannot unmarshal template data \n")
+		return tTemplate{}, err
 	}
 
+	return readTemplateFile(filePath, format)
+}
+
+func readTemplateFile(filePath string, format fileFormat) (tTemplate, error) {
+	var t templateFile
+	if err := readVersionedFile(filePath, format, templateMigrations, &t); err != nil {
+		return tTemplate{}, err
+	}
+
+	return normalizeTemplate(t.tTemplate, filePath)
+}
+
+// normalizeTemplate fills fields which are easy to leave out in hand written
+// templates. Windows and panes are created in the order they are listed and
+// window without panes gets a single pane in the root of the template.
+func normalizeTemplate(t tTemplate, filePath string) (tTemplate, error) {
+	if len(t.Windows) == 0 {
+		return tTemplate{}, fmt.Errorf("Template has no windows: %s \n", filePath)
+	}
+
+	windows := make([]tWindow, len(t.Windows))
+	for i, window := range t.Windows {
+		window.Order = int16(i + 1)
+
+		if len(window.Panes) == 0 {
+			window.Panes = []tPane{{Path: "."}}
+		} else {
+			window.Panes = append([]tPane(nil), window.Panes...)
+		}
+
+		for j := range window.Panes {
+			window.Panes[j].Order = int16(j + 1)
+		}
+
+		windows[i] = window
+	}
+	t.Windows = windows
+
 	return t, nil
 }
 
@@ -172,16 +211,14 @@ func loadTemplateFiles() ([]tTemplate, error) {
 	}
 
 	for _, e := range entries {
-		filePath := fmt.Sprintf("%s/%s", path, e.Name())
-		out, err := os.ReadFile(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("Unable to read tmux template file at path: %s \n", filePath)
+		format, ok := formatFromPath(e.Name())
+		if e.IsDir() || !ok {
+			continue
 		}
 
-		var t tTemplate
-		err = json.Unmarshal(out, &t)
+		t, err := readTemplateFile(filepath.Join(path, e.Name()), format)
 		if err != nil {
-			return nil, fmt.Errorf("Cannot unmarshal template data \n")
+			return nil, err
 		}
 
 		templates = append(templates, t)
@@ -190,31 +227,45 @@ func loadTemplateFiles() ([]tTemplate, error) {
 	return templates, nil
 }
 
-func saveTemplateFile(template tTemplate) error {
+// saveTemplateFile saves template in given format and returns path of the
+// file. Copies of the template in other formats are removed.
+func saveTemplateFile(template tTemplate, format fileFormat) (string, error) {
 	hasTemplatesDir, err := hasTemplatesDir()
 	if err != nil {
-		return fmt.Errorf("unable to create templates dir")
+		return "", fmt.Errorf("unable to create templates dir")
 	}
 
 	if !hasTemplatesDir {
 		if err := createTemplatesDir(); err != nil {
-			return fmt.Errorf("Cannot create template dir: ~%s \n", templatesDir)
+			return "", fmt.Errorf("Cannot create template dir: ~%s \n", templatesDir)
 		}
 	}
 
-	j, err := json.MarshalIndent(template, "", "  ")
+	out, err := encodeFile(templateFile{SchemaVersion: schemaVersion, tTemplate: template}, format)
 	if err != nil {
-		return fmt.Errorf("Cannot marshal template data")
+		return "", fmt.Errorf("Cannot marshal template data")
 	}
 
 	path, _ := getTemplatesDirPath()
-	filePath := fmt.Sprintf("%s/%s.json", path, template.Name)
-	err = os.WriteFile(filePath, j, 0644)
+	filePath := filepath.Join(path, template.Name+format.extension())
+	err = withConfigLock(func() error {
+		if err := writeFileAtomic(filePath, out, 0644); err != nil {
+			return err
+		}
+
+		for _, ext := range templateExtensions {
+			if other := filepath.Join(path, template.Name+ext); other != filePath {
+				os.Remove(other)
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("Cannot save template file at path: %s \n", filePath)
+		return "", fmt.Errorf("Cannot save template file at path: %s \n", filePath)
 	}
 
-	return nil
+	return filePath, nil
 }
 
 func deleteTemplateFile(templateName string) error {
@@ -223,13 +274,14 @@ func deleteTemplateFile(templateName string) error {
 		return fmt.Errorf("Unable to delete template \n")
 	}
 
-	path, _ := getTemplatesDirPath()
-	filePath := fmt.Sprintf("%s/%s.json", path, templateName)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return fmt.Errorf("Unable to delete template: %s \n", filePath)
+	filePath, _, err := findTemplateFile(templateName)
+	if err != nil {
+		return err
 	}
 
-	err = os.Remove(filePath)
+	err = withConfigLock(func() error {
+		return os.Remove(filePath)
+	})
 	if err != nil {
 		return fmt.Errorf("Unable to delete template: %s \n", filePath)
 	}
@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// controlClient speaks tmux control mode (tmux -C) over a single long-lived
+// connection. Every command gets its reply in a %begin ... %end or %begin ...
+// %error block, all other lines sent by tmux are notifications.
+type controlClient struct {
+	server tmuxServer
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	// mu keeps one command in flight, as replies come in order.
+	mu      sync.Mutex
+	replies chan controlReply
+	// notifications are closed when the connection ends.
+	notifications chan controlNotification
+}
+
+// controlNotification is a line like %window-add @1 sent by tmux when
+// something changes on the server.
+type controlNotification struct {
+	Name string
+	Args []string
+}
+
+type controlReply struct {
+	output []byte
+	err    error
+}
+
+// controlNotificationsBuffer is the number of notifications kept for a slow
+// reader. Further notifications are dropped until the reader catches up.
+const controlNotificationsBuffer = 64
+
+var errorControlClosed = errors.New("connection to tmux server closed")
+
+// newControlClient attaches control mode client to server. The server has to
+// be running with at least one session.
+func newControlClient(server tmuxServer) (*controlClient, error) {
+	cmd := exec.Command("tmux", append(server.args(), "-C", "attach-session")...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to tmux server: %s \n", server)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to tmux server: %s \n", server)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Unable to connect to tmux server: %s \n", server)
+	}
+
+	c := &controlClient{
+		server:        server,
+		cmd:           cmd,
+		stdin:         stdin,
+		replies:       make(chan controlReply),
+		notifications: make(chan controlNotification, controlNotificationsBuffer),
+	}
+
+	go c.read(stdout)
+
+	// Output of panes is not needed and the client must not shrink windows.
+	// Older tmux does not know the flags, which is fine.
+	if err := c.Run("refresh-client", "-f", "no-output,ignore-size"); errors.Is(err, errorControlClosed) {
+		c.Close()
+		return nil, fmt.Errorf("Unable to connect to tmux server: %s \n", server)
+	}
+
+	return c, nil
+}
+
+// Run executes tmux command and discards its output.
+func (c *controlClient) Run(args ...string) error {
+	_, err := c.Output(args...)
+	return err
+}
+
+// Output executes tmux command over the connection. Batched commands are
+// sent as one line, tmux replies to each of them until the first which fails.
+func (c *controlClient) Output(args ...string) ([]byte, error) {
+	line, commands, err := controlLine(args)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := io.WriteString(c.stdin, line+"\n"); err != nil {
+		return nil, errorControlClosed
+	}
+
+	var output []byte
+	for i := 0; i < commands; i++ {
+		reply, ok := <-c.replies
+		if !ok {
+			return nil, errorControlClosed
+		}
+
+		if reply.err != nil {
+			return nil, reply.err
+		}
+
+		output = append(output, reply.output...)
+	}
+
+	return output, nil
+}
+
+// Interactive needs a terminal, so it runs a separate tmux process.
+func (c *controlClient) Interactive(args ...string) error {
+	return execRunner{server: c.server}.Interactive(args...)
+}
+
+// Notifications returns channel of notifications sent by tmux. It is closed
+// when the connection ends.
+func (c *controlClient) Notifications() <-chan controlNotification {
+	return c.notifications
+}
+
+// Close detaches the client and waits for tmux to exit.
+func (c *controlClient) Close() error {
+	c.stdin.Close()
+	return c.cmd.Wait()
+}
+
+// read parses output of tmux until the connection ends. Replies to commands
+// of other clients, like the attach-session which started the connection,
+// are marked with flags 0 and skipped.
+func (c *controlClient) read(r io.Reader) {
+	defer close(c.notifications)
+	defer close(c.replies)
+
+	scanner := bufio.NewScanner(r)
+	// Captured pane content comes in long lines.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var (
+		inBlock bool
+		guard   []string
+		output  []byte
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if !inBlock {
+			if fields := strings.Fields(line); len(fields) == 4 && fields[0] == "%begin" {
+				inBlock, guard, output = true, fields, nil
+				continue
+			}
+
+			c.notify(line)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 4 || (fields[0] != "%end" && fields[0] != "%error") || fields[2] != guard[2] {
+			output = append(output, line+"\n"...)
+			continue
+		}
+
+		inBlock = false
+		if guard[3] != "1" {
+			continue
+		}
+
+		reply := controlReply{output: output}
+		if fields[0] == "%error" {
+			reply = controlReply{err: fmt.Errorf("%s", strings.TrimSpace(string(output)))}
+		}
+
+		c.replies <- reply
+	}
+}
+
+// notify passes notification line to the reader of notifications. Output
+// of panes is dropped.
+func (c *controlClient) notify(line string) {
+	if !strings.HasPrefix(line, "%") {
+		return
+	}
+
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 || fields[0] == "output" || fields[0] == "extended-output" {
+		return
+	}
+
+	select {
+	case c.notifications <- controlNotification{Name: fields[0], Args: fields[1:]}:
+	default:
+	}
+}
+
+// controlLine encodes tmux command as a line of control mode input and
+// returns number of commands in it. Arguments are quoted, commandSeparator
+// between batched commands is kept.
+func controlLine(args []string) (string, int, error) {
+	commands := splitCommands(args)
+
+	var words []string
+	for i, command := range commands {
+		if i > 0 {
+			words = append(words, commandSeparator)
+		}
+
+		for _, arg := range command {
+			if strings.ContainsAny(arg, "\r\n") {
+				return "", 0, fmt.Errorf("unable to send multi-line argument to tmux: %q", arg)
+			}
+
+			words = append(words, shellQuote(arg))
+		}
+	}
+
+	return strings.Join(words, " "), len(commands), nil
+}
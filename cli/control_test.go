@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// controlOutput is output of tmux -C for list-panes, a failing has-session and
+// new-window, recorded with tmux 3.3a, with pane output, a notification and a
+// stray %end line added. The reply with flags 0 belongs to attach-session
+// which started the connection.
+const controlOutput = `%begin 1792327028 263 0
+%end 1792327028 263 0
+%session-changed $0 work
+%begin 1792327028 264 1
+%1 work
+%end 1792327028 999 1
+%end 1792327028 264 1
+%output %1 \033[1mhello\033[0m
+%begin 1792327028 265 1
+can't find session: kee
+%error 1792327028 265 1
+%window-add @2
+%begin 1792327028 266 1
+%end 1792327028 266 1
+%exit
+`
+
+func TestControlClientRead(t *testing.T) {
+	c := &controlClient{
+		replies:       make(chan controlReply),
+		notifications: make(chan controlNotification, controlNotificationsBuffer),
+	}
+
+	go c.read(strings.NewReader(controlOutput))
+
+	var replies []controlReply
+	for reply := range c.replies {
+		replies = append(replies, reply)
+	}
+
+	if len(replies) != 3 {
+		t.Fatalf("got %d replies, want 3", len(replies))
+	}
+
+	// Lines starting with % and %end of other command are output.
+	if got, want := string(replies[0].output), "%1 work\n%end 1792327028 999 1\n"; got != want || replies[0].err != nil {
+		t.Errorf("list-panes reply = %q, %v, want %q", got, replies[0].err, want)
+	}
+
+	if err := replies[1].err; err == nil || err.Error() != "can't find session: kee" {
+		t.Errorf("has-session reply error = %v, want can't find session: kee", err)
+	}
+
+	if replies[2].output != nil || replies[2].err != nil {
+		t.Errorf("new-window reply = %q, %v, want empty", replies[2].output, replies[2].err)
+	}
+
+	var notifications []controlNotification
+	for n := range c.notifications {
+		notifications = append(notifications, n)
+	}
+
+	wantNotifications := []controlNotification{
+		{Name: "session-changed", Args: []string{"$0", "work"}},
+		{Name: "window-add", Args: []string{"@2"}},
+		{Name: "exit", Args: []string{}},
+	}
+	if !reflect.DeepEqual(notifications, wantNotifications) {
+		t.Errorf("notifications = %q, want %q", notifications, wantNotifications)
+	}
+}
+
+func TestControlLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		commands int
+		wantErr  bool
+	}{
+		{
+			name:     "single command",
+			args:     []string{"has-session", "-t", "=work"},
+			want:     "'has-session' '-t' '=work'",
+			commands: 1,
+		},
+		{
+			name:     "quotes",
+			args:     []string{"send-keys", "-t", "%1", "echo 'a b'", "Enter"},
+			want:     `'send-keys' '-t' '%1' 'echo '\''a b'\''' 'Enter'`,
+			commands: 1,
+		},
+		{
+			name:     "batch",
+			args:     []string{"rename-window", "-t", "=work:1", "code", commandSeparator, "send-keys", "-t", "=work:1.1", `echo a\;`, "Enter"},
+			want:     "'rename-window' '-t' '=work:1' 'code' ; 'send-keys' '-t' '=work:1.1' 'echo a;' 'Enter'",
+			commands: 2,
+		},
+		{
+			name:    "multi-line argument",
+			args:    []string{"send-keys", "-t", "%1", "a\nb"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, commands, err := controlLine(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("controlLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want || commands != tt.commands {
+				t.Errorf("controlLine() = %s, %d, want %s, %d", got, commands, tt.want, tt.commands)
+			}
+		})
+	}
+}
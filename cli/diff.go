@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffRenamed = "renamed"
+	diffLayout  = "layout"
+	diffPath    = "path"
+)
+
+// diffChange is a single difference between two states of sessions. Target
+// is the session, window (session:window) or pane (session:window.pane) in
+// the newer state, or in the older one when it was removed.
+type diffChange struct {
+	Object string `json:"object"`
+	Change string `json:"change"`
+	Target string `json:"target"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+type sessionsDiff struct {
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Changes []diffChange `json:"changes"`
+}
+
+// diffSessions compares sessions in from with sessions in to. Sessions are
+// matched by name, windows and panes by their index. A removed and an added
+// session with the same windows are reported as renamed.
+func diffSessions(from, to []tSession) []diffChange {
+	changes := []diffChange{}
+
+	var removed, added []tSession
+	for _, s := range from {
+		if _, ok := findSessionByName(to, s.Name); !ok {
+			removed = append(removed, s)
+		}
+	}
+	for _, s := range to {
+		if _, ok := findSessionByName(from, s.Name); !ok {
+			added = append(added, s)
+		}
+	}
+
+	for _, old := range from {
+		if s, ok := findSessionByName(to, old.Name); ok {
+			changes = append(changes, diffWindows(old, s)...)
+		}
+	}
+
+	for _, old := range removed {
+		i := findRenamedSession(added, old)
+		if i == -1 {
+			changes = append(changes, diffChange{Object: "session", Change: diffRemoved, Target: old.Name})
+			continue
+		}
+
+		s := added[i]
+		added = append(added[:i], added[i+1:]...)
+
+		changes = append(changes, diffChange{Object: "session", Change: diffRenamed, Target: s.Name, From: old.Name, To: s.Name})
+		changes = append(changes, diffWindows(old, s)...)
+	}
+
+	for _, s := range added {
+		changes = append(changes, diffChange{Object: "session", Change: diffAdded, Target: s.Name})
+	}
+
+	return changes
+}
+
+func diffWindows(from, to tSession) []diffChange {
+	var changes []diffChange
+
+	for _, old := range from.Windows {
+		target := fmt.Sprintf("%s:%d", to.Name, old.Order)
+
+		w, ok := findWindowByOrder(to, old.Order)
+		if !ok {
+			changes = append(changes, diffChange{Object: "window", Change: diffRemoved, Target: fmt.Sprintf("%s:%d", from.Name, old.Order), From: old.Name})
+			continue
+		}
+
+		if old.Name != w.Name {
+			changes = append(changes, diffChange{Object: "window", Change: diffRenamed, Target: target, From: old.Name, To: w.Name})
+		}
+
+		if layoutGeometry(old.Layout) != layoutGeometry(w.Layout) {
+			changes = append(changes, diffChange{Object: "window", Change: diffLayout, Target: target, From: old.Layout, To: w.Layout})
+		}
+
+		changes = append(changes, diffPanes(old, w, target)...)
+	}
+
+	for _, w := range to.Windows {
+		if _, ok := findWindowByOrder(from, w.Order); !ok {
+			changes = append(changes, diffChange{Object: "window", Change: diffAdded, Target: fmt.Sprintf("%s:%d", to.Name, w.Order), To: w.Name})
+		}
+	}
+
+	return changes
+}
+
+var (
+	// layoutChecksum is the checksum which prefixes layout of tmux window.
+	layoutChecksum = regexp.MustCompile(`^[0-9a-f]{4},`)
+	// layoutPane matches cell of a single pane, WxH,X,Y followed by pane id.
+	layoutPane = regexp.MustCompile(`(\d+x\d+,\d+,\d+),\d+`)
+)
+
+// layoutGeometry returns layout without its checksum and pane ids, which
+// change with every restore while sizes and positions of panes stay.
+func layoutGeometry(layout string) string {
+	layout = layoutChecksum.ReplaceAllString(layout, "")
+
+	return layoutPane.ReplaceAllString(layout, "$1")
+}
+
+func diffPanes(from, to tWindow, window string) []diffChange {
+	var changes []diffChange
+
+	for _, old := range from.Panes {
+		target := fmt.Sprintf("%s.%d", window, old.Order)
+
+		p, ok := findPaneByOrder(to, old.Order)
+		if !ok {
+			changes = append(changes, diffChange{Object: "pane", Change: diffRemoved, Target: target, From: old.Path})
+			continue
+		}
+
+		if old.Name != p.Name {
+			changes = append(changes, diffChange{Object: "pane", Change: diffRenamed, Target: target, From: old.Name, To: p.Name})
+		}
+
+		if old.Path != p.Path {
+			changes = append(changes, diffChange{Object: "pane", Change: diffPath, Target: target, From: old.Path, To: p.Path})
+		}
+	}
+
+	for _, p := range to.Panes {
+		if _, ok := findPaneByOrder(from, p.Order); !ok {
+			changes = append(changes, diffChange{Object: "pane", Change: diffAdded, Target: fmt.Sprintf("%s.%d", window, p.Order), To: p.Path})
+		}
+	}
+
+	return changes
+}
+
+// findRenamedSession returns index of session in candidates with the same
+// window names as s, or -1.
+func findRenamedSession(candidates []tSession, s tSession) int {
+	for i, c := range candidates {
+		if windowNames(c) == windowNames(s) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func windowNames(s tSession) string {
+	var names []string
+	for _, w := range s.Windows {
+		names = append(names, w.Name)
+	}
+
+	return strings.Join(names, "\n")
+}
+
+func findSessionByName(sessions []tSession, name string) (tSession, bool) {
+	for _, s := range sessions {
+		if s.Name == name {
+			return s, true
+		}
+	}
+
+	return tSession{}, false
+}
+
+func findWindowByOrder(s tSession, order int16) (tWindow, bool) {
+	for _, w := range s.Windows {
+		if w.Order == order {
+			return w, true
+		}
+	}
+
+	return tWindow{}, false
+}
+
+func findPaneByOrder(w tWindow, order int16) (tPane, bool) {
+	for _, p := range w.Panes {
+		if p.Order == order {
+			return p, true
+		}
+	}
+
+	return tPane{}, false
+}
+
+// renderDiff prints changes as a table, one change per row.
+func renderDiff(changes []diffChange) {
+	symbols := map[string]string{diffAdded: "+", diffRemoved: "-"}
+
+	var d [][]string
+	for _, c := range changes {
+		symbol, ok := symbols[c.Change]
+		if !ok {
+			symbol = "~"
+		}
+
+		detail := c.From + c.To
+		if c.From != "" && c.To != "" {
+			detail = fmt.Sprintf("%s -> %s", c.From, c.To)
+		}
+
+		d = append(d, []string{symbol, fmt.Sprintf("%s %s", c.Object, c.Change), c.Target, detail})
+	}
+
+	renderTable(d)
+}
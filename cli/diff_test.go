@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayoutGeometry(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{"b25f,80x24,0,0,1", "80x24,0,0"},
+		{"d67e,80x24,0,0{40x24,0,0,0,39x24,41,0[39x12,41,0,1,39x11,41,13,2]}", "80x24,0,0{40x24,0,0,39x24,41,0[39x12,41,0,39x11,41,13]}"},
+		{"tiled", "tiled"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := layoutGeometry(tt.layout); got != tt.want {
+			t.Errorf("layoutGeometry(%q) = %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestDiffWindowsLayout(t *testing.T) {
+	from := newTestSession("work", []string{"/tmp", "/usr"})
+	to := newTestSession("work", []string{"/tmp", "/usr"})
+
+	// Same geometry after restore, with new pane ids and checksum.
+	from.Windows[0].Layout = "5e0a,80x24,0,0{40x24,0,0,1,39x24,41,0,2}"
+	to.Windows[0].Layout = "5e2c,80x24,0,0{40x24,0,0,7,39x24,41,0,8}"
+	if changes := diffWindows(from, to); len(changes) != 0 {
+		t.Errorf("diffWindows() = %v, want no changes", changes)
+	}
+
+	to.Windows[0].Layout = "a1b2,80x24,0,0[80x12,0,0,7,80x11,0,13,8]"
+	want := []diffChange{{Object: "window", Change: diffLayout, Target: "work:1", From: from.Windows[0].Layout, To: to.Windows[0].Layout}}
+	if changes := diffWindows(from, to); !reflect.DeepEqual(changes, want) {
+		t.Errorf("diffWindows() = %v, want %v", changes, want)
+	}
+}
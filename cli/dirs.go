@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const appDir = "tmxu"
+
+// configDirFlag is set by the global --config-dir flag.
+var configDirFlag string
+
+// customHomeDir returns dir set with --config-dir or $TMXU_HOME. It holds
+// both config and state of tmxu.
+func customHomeDir() (string, error) {
+	dir := configDirFlag
+	if dir == "" {
+		dir = os.Getenv("TMXU_HOME")
+	}
+
+	if dir == "" {
+		return "", nil
+	}
+
+	return filepath.Abs(expandHome(dir))
+}
+
+// getConfigDirPath returns dir of templates and saved sessions: --config-dir,
+// $TMXU_HOME, $XDG_CONFIG_HOME/tmxu or ~/.config/tmxu.
+func getConfigDirPath() (string, error) {
+	return getAppDirPath("XDG_CONFIG_HOME", ".config")
+}
+
+// getStateDirPath returns dir of snapshots and scrollback: --config-dir,
+// $TMXU_HOME, $XDG_STATE_HOME/tmxu or ~/.local/state/tmxu.
+func getStateDirPath() (string, error) {
+	return getAppDirPath("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// getAppDirPath returns tmxu dir inside of dir in XDG environment variable,
+// falling back to homeSubdir of the home dir. Relative XDG paths are
+// ignored as the specification requires.
+func getAppDirPath(xdgVar, homeSubdir string) (string, error) {
+	custom, err := customHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("Unable to resolve tmxu home dir \n")
+	}
+
+	if custom != "" {
+		return custom, nil
+	}
+
+	if xdg := os.Getenv(xdgVar); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, appDir), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("Unable to get home dir \n")
+	}
+
+	return filepath.Join(homeDir, homeSubdir, appDir), nil
+}
+
+// getStateSubdirPath returns path of dir name inside of the state dir. Older
+// tmxu kept it in the config dir, such dir is moved to the state dir on first
+// use, or used in place when it cannot be moved.
+func getStateSubdirPath(name string) (string, error) {
+	stateDir, err := getStateDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	configDir, err := getConfigDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(stateDir, name)
+	legacy := filepath.Join(configDir, name)
+	if path == legacy {
+		return path, nil
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return path, nil
+	}
+
+	if _, err := os.Stat(legacy); err != nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(stateDir, 0755); err == nil {
+		if err := os.Rename(legacy, path); err == nil {
+			return path, nil
+		}
+	}
+
+	return legacy, nil
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readOnlyCommands are tmux commands which only query the server.
+var readOnlyCommands = []string{
+	"list-sessions", "list-windows", "list-panes", "has-session", "capture-pane", "display-message",
+}
+
+// planRunner prints tmux commands which change the server instead of running
+// them. Every command is passed to backend, an in-memory copy of the server,
+// so queries made later in the plan see the effects of planned commands.
+type planRunner struct {
+	backend TmuxRunner
+	out     io.Writer
+}
+
+func (p planRunner) Run(args ...string) error {
+	_, err := p.Output(args...)
+	return err
+}
+
+// Output prints every command of a batch on its own line. Commands run on
+// backend one by one, so pane ids can be resolved to targets they point to at
+// that moment.
+func (p planRunner) Output(args ...string) ([]byte, error) {
+	var out []byte
+
+	for _, command := range splitCommands(args) {
+		if len(command) > 0 && !contains(readOnlyCommands, command[0]) {
+			fmt.Fprintf(p.out, "  %s\n", formatTmuxCommand(p.resolveTargets(command)))
+		}
+
+		escaped := make([]string, len(command))
+		for i, a := range command {
+			escaped[i] = escapeSeparator(a)
+		}
+
+		o, err := p.backend.Output(escaped...)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, o...)
+	}
+
+	return out, nil
+}
+
+// resolveTargets replaces pane ids given with -t, which exist only in the
+// backend, with session:window.pane targets.
+func (p planRunner) resolveTargets(command []string) []string {
+	resolved := append([]string(nil), command...)
+
+	for i := 1; i < len(resolved); i++ {
+		if resolved[i-1] != "-t" || !strings.HasPrefix(resolved[i], "%") {
+			continue
+		}
+
+		out, err := p.backend.Output("display-message", "-p", "-t", resolved[i], "#{session_name}:#{window_index}.#{pane_index}")
+		if err == nil {
+			resolved[i] = exactTarget(strings.TrimSpace(string(out)))
+		}
+	}
+
+	return resolved
+}
+
+func (p planRunner) Interactive(args ...string) error {
+	return p.Run(args...)
+}
+
+// startDryRun swaps tmux runner and hooks for ones printing the plan based on
+// current state of the server. Returned function restores them.
+func startDryRun() func() {
+	sessions, err := captureSessions()
+	if err != nil {
+		// No server running, plan against an empty one.
+		sessions = nil
+	}
+
+	prevRunner, prevHook := runner, runHook
+
+	runner = planRunner{backend: newFakeTmuxFrom(sessions), out: os.Stdout}
+	runHook = func(command, dir, sessionName string) error {
+		fmt.Printf("  (cd %s && %s)\n", quoteArg(dir), command)
+		return nil
+	}
+
+	fmt.Println("Dry run, nothing is changed. Planned operations:")
+
+	return func() {
+		runner, runHook = prevRunner, prevHook
+	}
+}
+
+// formatTmuxCommand renders single tmux command so it can be pasted into a
+// shell.
+func formatTmuxCommand(args []string) string {
+	quoted := []string{"tmux"}
+	for _, a := range append(activeServer.args(), args...) {
+		quoted = append(quoted, quoteArg(escapeSeparator(a)))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg quotes shell argument only when it is needed.
+func quoteArg(s string) string {
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("-_./:=@%+,", r) &&
+			!('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		return shellQuote(s)
+	}
+
+	return s
+}
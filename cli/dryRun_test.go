@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPlanRunnerPrintsCommandPerLine(t *testing.T) {
+	var out bytes.Buffer
+
+	f := useFakeTmux(t, newTestSession("work", []string{"/tmp"}))
+	runner = planRunner{backend: f, out: &out}
+
+	err := batched(func() error {
+		runner.Run("rename-window", "-t", "work:1", "my code")
+		runner.Run("send-keys", "-t", "work:1.1", "echo a;", "Enter")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("batched() error = %v", err)
+	}
+
+	if _, err := runner.Output("list-panes", "-a"); err != nil {
+		t.Fatalf("list-panes error = %v", err)
+	}
+
+	want := "  tmux rename-window -t work:1 'my code'\n" +
+		"  tmux send-keys -t work:1.1 'echo a\\;' Enter\n"
+	if out.String() != want {
+		t.Errorf("plan =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPlanRunnerResolvesPaneIDs(t *testing.T) {
+	var out bytes.Buffer
+
+	useFakeTmux(t, newTestSession("work", []string{"/tmp"}))
+	tmpl := tTemplate{Windows: []tWindow{
+		{Order: 1, Name: "code", Panes: []tPane{
+			{Order: 1, Path: ".", Commands: []string{"vim"}},
+			{Order: 2, Path: ".", Commands: []string{"make run"}},
+		}},
+	}}
+
+	runner = planRunner{backend: runner, out: &out}
+	if err := newSessionFromTemplate(tmpl, "app", t.TempDir(), nil); err != nil {
+		t.Fatalf("newSessionFromTemplate() error = %v", err)
+	}
+
+	// Ids of panes exist only in the plan, targets are printed instead.
+	for _, want := range []string{
+		"tmux select-pane -t =app:1.2 -T ''",
+		"tmux send-keys -t =app:1.1 vim Enter",
+		"tmux send-keys -t =app:1.2 'make run' Enter",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if strings.Contains(out.String(), "-t %") {
+		t.Errorf("plan targets pane ids:\n%s", out.String())
+	}
+}
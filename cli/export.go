@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	exportTmuxinator = "tmuxinator"
+	exportTmuxp      = "tmuxp"
+	exportShell      = "sh"
+)
+
+// exportTemplate renders template with expanded placeholders in the format of
+// another tool. Returns rendered file and warnings about parts of the
+// template the format cannot express.
+func exportTemplate(t tTemplate, kind string) ([]byte, []string, error) {
+	switch kind {
+	case exportTmuxinator:
+		return exportTmuxinatorProject(t)
+	case exportTmuxp:
+		return exportTmuxpWorkspace(t)
+	case exportShell:
+		return exportShellScript(t), nil, nil
+	}
+
+	return nil, nil, fmt.Errorf("Unsupported export format: %s. Use tmuxinator, tmuxp or sh \n", kind)
+}
+
+type tmuxinatorProject struct {
+	Name           string                        `json:"name"`
+	Root           string                        `json:"root,omitempty"`
+	OnProjectStart []string                      `json:"on_project_start,omitempty"`
+	Windows        []map[string]tmuxinatorWindow `json:"windows"`
+}
+
+type tmuxinatorWindow struct {
+	Root   string   `json:"root,omitempty"`
+	Layout string   `json:"layout,omitempty"`
+	Pre    []string `json:"pre,omitempty"`
+	Panes  []any    `json:"panes"`
+}
+
+// exportTmuxinatorProject renders tmuxinator project. Every pane starts in the
+// window root, panes in other directories cd into them first.
+func exportTmuxinatorProject(t tTemplate) ([]byte, []string, error) {
+	var warnings []string
+
+	p := tmuxinatorProject{
+		Name:           t.Name,
+		Root:           t.Root,
+		OnProjectStart: t.Pre,
+	}
+
+	if len(t.Post) > 0 {
+		warnings = append(warnings, "post hooks are not supported by tmuxinator")
+	}
+
+	for _, w := range t.Windows {
+		window := tmuxinatorWindow{
+			Layout: w.Layout,
+			Pre:    w.Commands,
+		}
+
+		dir := "."
+		if len(w.Panes) > 0 {
+			dir = exportPath(w.Panes[0].Path, t.Root)
+		}
+
+		if dir != "." {
+			window.Root = dir
+		}
+
+		for _, pane := range w.Panes {
+			var commands []string
+			if path := exportPath(pane.Path, t.Root); path != dir {
+				commands = append(commands, "cd "+exportCdPath(path, dir))
+			}
+			commands = append(commands, exportPaneCommands(pane)...)
+
+			switch {
+			case pane.Name != "":
+				window.Panes = append(window.Panes, map[string][]string{pane.Name: commands})
+			case len(commands) == 0:
+				window.Panes = append(window.Panes, nil)
+			case len(commands) == 1:
+				window.Panes = append(window.Panes, commands[0])
+			default:
+				window.Panes = append(window.Panes, commands)
+			}
+		}
+
+		p.Windows = append(p.Windows, map[string]tmuxinatorWindow{w.Name: window})
+	}
+
+	out, err := encodeFile(p, formatYAML)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot marshal tmuxinator project \n")
+	}
+
+	return append([]byte(exportHeader(t)), out...), warnings, nil
+}
+
+type tmuxpWorkspace struct {
+	SessionName    string        `json:"session_name"`
+	StartDirectory string        `json:"start_directory,omitempty"`
+	BeforeScript   string        `json:"before_script,omitempty"`
+	Windows        []tmuxpWindow `json:"windows"`
+}
+
+type tmuxpWindow struct {
+	WindowName         string      `json:"window_name"`
+	Layout             string      `json:"layout,omitempty"`
+	ShellCommandBefore []string    `json:"shell_command_before,omitempty"`
+	Panes              []tmuxpPane `json:"panes"`
+}
+
+type tmuxpPane struct {
+	ShellCommand   []string `json:"shell_command,omitempty"`
+	StartDirectory string   `json:"start_directory,omitempty"`
+}
+
+// exportTmuxpWorkspace renders tmuxp workspace. Pane directories are relative
+// to the session start directory.
+func exportTmuxpWorkspace(t tTemplate) ([]byte, []string, error) {
+	var warnings []string
+
+	ws := tmuxpWorkspace{
+		SessionName:    t.Name,
+		StartDirectory: t.Root,
+	}
+
+	switch {
+	case len(t.Pre) == 1:
+		ws.BeforeScript = t.Pre[0]
+	case len(t.Pre) > 1:
+		ws.BeforeScript = "sh -c " + shellQuote(strings.Join(t.Pre, " && "))
+	}
+
+	if len(t.Post) > 0 {
+		warnings = append(warnings, "post hooks are not supported by tmuxp")
+	}
+
+	for _, w := range t.Windows {
+		window := tmuxpWindow{
+			WindowName:         w.Name,
+			Layout:             w.Layout,
+			ShellCommandBefore: w.Commands,
+		}
+
+		for _, pane := range w.Panes {
+			if pane.Name != "" {
+				warnings = append(warnings, fmt.Sprintf("pane titles are not supported by tmuxp: %s", pane.Name))
+			}
+
+			p := tmuxpPane{ShellCommand: exportPaneCommands(pane)}
+			if pane.Path != "." {
+				p.StartDirectory = exportPath(pane.Path, t.Root)
+			}
+
+			window.Panes = append(window.Panes, p)
+		}
+
+		ws.Windows = append(ws.Windows, window)
+	}
+
+	out, err := encodeFile(ws, formatYAML)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot marshal tmuxp workspace \n")
+	}
+
+	return append([]byte(exportHeader(t)), out...), warnings, nil
+}
+
+// exportShellScript renders bash script which creates the session with plain
+// tmux commands. Session name and project root can be passed as arguments.
+func exportShellScript(t tTemplate) []byte {
+	var b strings.Builder
+
+	root := t.Root
+	if root == "" {
+		root = "."
+	}
+
+	fmt.Fprintln(&b, "#!/usr/bin/env bash")
+	b.WriteString(exportHeader(t))
+	fmt.Fprintln(&b, "# Usage: script [session-name] [project-root]")
+	fmt.Fprintln(&b, "set -e")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "SESSION=\"${1:-%s}\"\n", escapeDoubleQuoted(t.Name))
+	fmt.Fprintf(&b, "ROOT=\"${2:-%s}\"\n", exportShellRoot(root))
+	// Hooks can read the session name like when run by tmxu.
+	fmt.Fprintln(&b, `export TMXU_SESSION="$SESSION"`)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, `if tmux has-session -t "=$SESSION" 2>/dev/null; then`)
+	fmt.Fprintln(&b, `  echo "Session already exist: $SESSION"`)
+	fmt.Fprintln(&b, "  exit 1")
+	fmt.Fprintln(&b, "fi")
+	fmt.Fprintln(&b)
+
+	if len(t.Pre) > 0 {
+		fmt.Fprintln(&b, `(cd "$ROOT"`)
+		for _, hook := range t.Pre {
+			fmt.Fprintf(&b, "  %s\n", hook)
+		}
+		fmt.Fprintln(&b, ")")
+		fmt.Fprintln(&b)
+	}
+
+	for i, w := range t.Windows {
+		// Windows and panes are targeted by id, so the script works with any
+		// base-index and index of a pane can change when a pane before it is
+		// split. Each pane is split from the previous one.
+		for j, pane := range w.Panes {
+			dir := exportShellPath(exportPath(pane.Path, t.Root))
+			target := `"$pane"`
+
+			switch {
+			case i == 0 && j == 0:
+				fmt.Fprintf(&b, "ids=$(tmux new-session -d -P -F '#{window_id} #{pane_id}' -s \"$SESSION\" -n %s -c %s)\n", shellQuote(w.Name), dir)
+			case j == 0:
+				fmt.Fprintf(&b, "ids=$(tmux new-window -a -P -F '#{window_id} #{pane_id}' -t \"$window\" -n %s -c %s)\n", shellQuote(w.Name), dir)
+			default:
+				fmt.Fprintf(&b, "pane=$(tmux split-window -d -P -F '#{pane_id}' -t \"$pane\" -c %s)\n", dir)
+			}
+
+			if j == 0 {
+				fmt.Fprintln(&b, `window="${ids% *}"`)
+				fmt.Fprintln(&b, `pane="${ids#* }"`)
+			}
+
+			if pane.Name != "" {
+				fmt.Fprintf(&b, "tmux select-pane -t %s -T %s\n", target, shellQuote(pane.Name))
+			}
+
+			commands := append(append([]string(nil), w.Commands...), exportPaneCommands(pane)...)
+			for _, c := range commands {
+				fmt.Fprintf(&b, "tmux send-keys -t %s %s Enter\n", target, shellQuote(c))
+			}
+		}
+
+		if w.Layout != "" {
+			fmt.Fprintf(&b, "tmux select-layout -t \"$window\" %s\n", shellQuote(w.Layout))
+		}
+
+		fmt.Fprintln(&b)
+	}
+
+	if len(t.Post) > 0 {
+		fmt.Fprintln(&b, `(cd "$ROOT"`)
+		for _, hook := range t.Post {
+			fmt.Fprintf(&b, "  %s\n", hook)
+		}
+		fmt.Fprintln(&b, ")")
+		fmt.Fprintln(&b)
+	}
+
+	fmt.Fprintln(&b, `echo "Session: $SESSION created! Run \"tmux attach -t $SESSION\" to use it"`)
+
+	return []byte(b.String())
+}
+
+// exportPaneCommands returns commands of pane followed by its captured
+// program when restore would relaunch it.
+func exportPaneCommands(pane tPane) []string {
+	commands := append([]string(nil), pane.Commands...)
+
+	if newCommandPolicy("", "").allowed(pane.CommandLine) {
+		commands = append(commands, pane.CommandLine)
+	}
+
+	return commands
+}
+
+// exportPath returns pane path relative to root when possible.
+func exportPath(path, root string) string {
+	if root == "" || !filepath.IsAbs(path) && !strings.HasPrefix(path, "~") {
+		return path
+	}
+
+	return relativePanePath(path, root)
+}
+
+// exportCdPath returns quoted argument of cd from window dir to pane path.
+func exportCdPath(path, dir string) string {
+	if !filepath.IsAbs(path) && !strings.HasPrefix(path, "~") && !filepath.IsAbs(dir) && !strings.HasPrefix(dir, "~") {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			return shellQuote(rel)
+		}
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return "~" + shellQuote(path[1:])
+	}
+
+	return shellQuote(path)
+}
+
+// exportShellRoot quotes root for use inside double quotes keeping ~ working.
+func exportShellRoot(root string) string {
+	if root == "~" || strings.HasPrefix(root, "~/") {
+		return "$HOME" + escapeDoubleQuoted(root[1:])
+	}
+
+	return escapeDoubleQuoted(root)
+}
+
+// escapeDoubleQuoted escapes characters with special meaning inside of double
+// quotes in shell.
+func escapeDoubleQuoted(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(s)
+}
+
+// exportShellPath returns shell expression of pane directory.
+func exportShellPath(path string) string {
+	switch {
+	case path == "." || path == "":
+		return `"$ROOT"`
+	case path == "~" || strings.HasPrefix(path, "~/"):
+		return `"$HOME"` + shellQuote(path[1:])
+	case filepath.IsAbs(path):
+		return shellQuote(path)
+	}
+
+	return `"$ROOT"/` + shellQuote(path)
+}
+
+func exportHeader(t tTemplate) string {
+	return fmt.Sprintf("# Exported by tmxu from template: %s\n", t.Name)
+}
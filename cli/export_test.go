@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportShellScriptDefaultBaseIndex(t *testing.T) {
+	tmuxPath, err := exec.LookPath("tmux")
+	if err != nil {
+		t.Skip("tmux is not installed")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	// Server without user config, so windows and panes are indexed from 0.
+	server := tmuxServer{SocketName: fmt.Sprintf("tmxu-export-%d", os.Getpid())}
+	tmux := execRunner{server: server}
+	t.Cleanup(func() { tmux.Run("kill-server") })
+
+	bin := t.TempDir()
+	wrapper := fmt.Sprintf("#!/bin/sh\nexec %s -L %s -f /dev/null \"$@\"\n", tmuxPath, server.SocketName)
+	if err := os.WriteFile(filepath.Join(bin, "tmux"), []byte(wrapper), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := tTemplate{
+		Name: "app",
+		Windows: []tWindow{
+			{Order: 1, Name: "code", Layout: "tiled", Panes: []tPane{
+				{Order: 1, Name: "editor", Path: "."},
+				{Order: 2, Path: "."},
+			}},
+			{Order: 2, Name: "logs", Layout: "tiled", Panes: []tPane{{Order: 1, Path: "."}}},
+		},
+	}
+
+	script := filepath.Join(t.TempDir(), "app.sh")
+	if err := os.WriteFile(script, exportShellScript(tmpl), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("bash", script, "app", t.TempDir())
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"), "SHELL=/bin/sh")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("script error = %v\n%s", err, out)
+	}
+
+	out, err := tmux.Output("list-panes", "-s", "-t", "app", "-F", "#{window_name}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := strings.Fields(string(out)), []string{"code", "code", "logs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("panes of windows = %q, want %q", got, want)
+	}
+}
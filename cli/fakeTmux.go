@@ -0,0 +1,780 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fakeTmux is an in-memory TmuxRunner which models sessions, windows and panes
+// of a tmux server. It understands the subset of tmux commands used by tmxu,
+// so the save/restore/template logic can be exercised without a live server.
+// Both base-index and pane-base-index are 1, as tmxu expects.
+type fakeTmux struct {
+	sessions []*fakeSession
+	clock    int64
+	// pid is the last pid handed out to a pane.
+	pid int
+	// paneID is the last pane id handed out, ids are unique on the server.
+	paneID int
+	// calls holds every command executed against the fake, in order.
+	calls [][]string
+	// failTarget makes every command with -t of this value fail, to test
+	// commands failing partway.
+	failTarget string
+}
+
+type fakeSession struct {
+	name    string
+	created int64
+	windows []*fakeWindow
+}
+
+type fakeWindow struct {
+	index  int
+	name   string
+	layout string
+	panes  []*fakePane
+	active *fakePane
+}
+
+type fakePane struct {
+	// id is the unique pane id, like %3.
+	id      string
+	index   int
+	title   string
+	path    string
+	command string
+	pid     int
+	// content is returned by capture-pane.
+	content string
+	// keys collects everything sent to the pane with send-keys.
+	keys []string
+}
+
+const fakeDefaultLayout = "tiled"
+const fakeDefaultWindowName = "shell"
+const fakeDefaultCommand = "zsh"
+
+func newFakeTmux() *fakeTmux {
+	return &fakeTmux{clock: 1700000000}
+}
+
+// newFakeTmuxFrom returns fake with the layout of captured sessions, e.g. a
+// copy of the live server.
+func newFakeTmuxFrom(sessions []tSession) *fakeTmux {
+	f := newFakeTmux()
+
+	for _, ts := range sessions {
+		f.clock++
+		s := &fakeSession{name: ts.Name, created: f.clock}
+
+		for _, tw := range ts.Windows {
+			w := &fakeWindow{index: int(tw.Order), name: tw.Name, layout: tw.Layout}
+
+			for _, tp := range tw.Panes {
+				p := f.newPane(int(tp.Order), tp.Path)
+				p.title = tp.Name
+				if tp.Command != "" {
+					p.command = tp.Command
+				}
+
+				w.panes = append(w.panes, p)
+			}
+
+			if len(w.panes) > 0 {
+				w.active = w.panes[0]
+			}
+
+			s.windows = append(s.windows, w)
+		}
+
+		f.sessions = append(f.sessions, s)
+	}
+
+	return f
+}
+
+func (f *fakeTmux) Run(args ...string) error {
+	_, err := f.Output(args...)
+	return err
+}
+
+func (f *fakeTmux) Interactive(args ...string) error {
+	_, err := f.Output(args...)
+	return err
+}
+
+// Output runs commands separated with commandSeparator one after another,
+// stopping at the first which fails, like tmux does.
+func (f *fakeTmux) Output(args ...string) ([]byte, error) {
+	f.calls = append(f.calls, args)
+
+	var out []byte
+	for _, command := range splitCommands(args) {
+		o, err := f.exec(command)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, o...)
+	}
+
+	return out, nil
+}
+
+func (f *fakeTmux) exec(args []string) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("fake tmux: no command")
+	}
+
+	cmd, rest := args[0], args[1:]
+	if f.failTarget != "" && contains(rest, "-t") && contains(rest, f.failTarget) {
+		return nil, fmt.Errorf("fake tmux: %s failed for target: %s", cmd, f.failTarget)
+	}
+
+	switch cmd {
+	case "list-sessions":
+		return f.listSessions(rest)
+	case "has-session":
+		return f.hasSession(rest)
+	case "new-session":
+		return f.newSession(rest)
+	case "kill-session":
+		return f.killSession(rest)
+	case "rename-session":
+		return f.renameSession(rest)
+	case "attach", "attach-session":
+		return f.hasSession(rest)
+	case "list-windows":
+		return f.listWindows(rest)
+	case "new-window":
+		return f.newWindow(rest)
+	case "rename-window":
+		return f.renameWindow(rest)
+	case "kill-window":
+		return f.killWindow(rest)
+	case "select-layout":
+		return f.selectLayout(rest)
+	case "list-panes":
+		return f.listPanes(rest)
+	case "split-window":
+		return f.splitWindow(rest)
+	case "kill-pane":
+		return f.killPane(rest)
+	case "select-pane":
+		return f.selectPane(rest)
+	case "send-keys":
+		return f.sendKeys(rest)
+	case "capture-pane":
+		return f.capturePane(rest)
+	case "display-message":
+		return f.displayMessage(rest)
+	}
+
+	return nil, fmt.Errorf("fake tmux: unknown command: %s", cmd)
+}
+
+func (f *fakeTmux) listSessions(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "F")
+
+	if len(f.sessions) == 0 {
+		return nil, fmt.Errorf("fake tmux: no server running")
+	}
+
+	var lines []string
+	for _, s := range f.sessions {
+		lines = append(lines, expandFakeFormat(flags["F"], s.vars()))
+	}
+
+	return fakeLines(lines), nil
+}
+
+func (f *fakeTmux) hasSession(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "t")
+
+	if _, err := f.findSession(flags["t"]); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (f *fakeTmux) newSession(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "csn")
+
+	name := flags["s"]
+	if name == "" {
+		name = strconv.Itoa(len(f.sessions))
+	}
+
+	if s, _ := f.findSession("=" + name); s != nil {
+		return nil, fmt.Errorf("fake tmux: duplicate session: %s", name)
+	}
+
+	windowName := flags["n"]
+	if windowName == "" {
+		windowName = fakeDefaultWindowName
+	}
+
+	f.clock++
+	f.sessions = append(f.sessions, &fakeSession{
+		name:    name,
+		created: f.clock,
+		windows: []*fakeWindow{f.newWindowWithPane(1, windowName, flags["c"])},
+	})
+
+	return nil, nil
+}
+
+func (f *fakeTmux) killSession(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "t")
+
+	s, err := f.findSession(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	f.removeSession(s)
+	return nil, nil
+}
+
+func (f *fakeTmux) renameSession(args []string) ([]byte, error) {
+	flags, rest := parseFakeArgs(args, "t")
+
+	s, err := f.findSession(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) == 0 {
+		return nil, fmt.Errorf("fake tmux: missing session name")
+	}
+
+	if other, _ := f.findSession("=" + rest[0]); other != nil {
+		return nil, fmt.Errorf("fake tmux: duplicate session: %s", rest[0])
+	}
+
+	s.name = rest[0]
+
+	return nil, nil
+}
+
+func (f *fakeTmux) listWindows(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "tF")
+
+	s, err := f.findSession(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, w := range s.windows {
+		lines = append(lines, expandFakeFormat(flags["F"], w.vars(s)))
+	}
+
+	return fakeLines(lines), nil
+}
+
+func (f *fakeTmux) newWindow(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "ctn")
+
+	sessionName, windowIndex, _ := splitFakeTarget(flags["t"])
+	s, err := f.findSession(sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	index := 1
+	for _, w := range s.windows {
+		index = Max(index, w.index+1)
+	}
+
+	if windowIndex != "" {
+		i, err := strconv.Atoi(windowIndex)
+		if err != nil {
+			return nil, fmt.Errorf("fake tmux: invalid window index: %s", windowIndex)
+		}
+
+		if _, err := s.findWindow(windowIndex); err == nil {
+			return nil, fmt.Errorf("fake tmux: index in use: %d", i)
+		}
+
+		index = i
+	}
+
+	name := flags["n"]
+	if name == "" {
+		name = fakeDefaultWindowName
+	}
+
+	s.windows = append(s.windows, f.newWindowWithPane(index, name, flags["c"]))
+
+	return nil, nil
+}
+
+func (f *fakeTmux) renameWindow(args []string) ([]byte, error) {
+	flags, rest := parseFakeArgs(args, "t")
+
+	w, err := f.findWindow(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) > 0 {
+		w.name = rest[0]
+	}
+
+	return nil, nil
+}
+
+func (f *fakeTmux) selectLayout(args []string) ([]byte, error) {
+	flags, rest := parseFakeArgs(args, "t")
+
+	w, err := f.findWindow(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) > 0 {
+		w.layout = rest[0]
+	}
+
+	return nil, nil
+}
+
+// listPanes lists panes of the target window, of every window of the target
+// session with -s, or of the whole server with -a.
+func (f *fakeTmux) listPanes(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "tF")
+	_, all := flags["a"]
+	_, inSession := flags["s"]
+
+	sessions := f.sessions
+	if all && len(sessions) == 0 {
+		return nil, fmt.Errorf("fake tmux: no server running")
+	}
+
+	if !all {
+		sessionName, _, _ := splitFakeTarget(flags["t"])
+		s, err := f.findSession(sessionName)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = []*fakeSession{s}
+	}
+
+	var lines []string
+	for _, s := range sessions {
+		windows := s.windows
+		if !all && !inSession {
+			w, err := f.findWindow(flags["t"])
+			if err != nil {
+				return nil, err
+			}
+
+			windows = []*fakeWindow{w}
+		}
+
+		for _, w := range windows {
+			for _, p := range w.panes {
+				lines = append(lines, expandFakeFormat(flags["F"], p.vars(s, w)))
+			}
+		}
+	}
+
+	return fakeLines(lines), nil
+}
+
+// splitWindow inserts new pane right after the target pane and renumbers
+// the panes behind it, like tmux. With -d the active pane stays active.
+func (f *fakeTmux) splitWindow(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "ctF")
+
+	s, w, target, err := f.findPane(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	p := f.newPane(0, flags["c"])
+
+	var panes []*fakePane
+	for _, wp := range w.panes {
+		panes = append(panes, wp)
+		if wp == target {
+			panes = append(panes, p)
+		}
+	}
+
+	w.panes = panes
+	for i, wp := range w.panes {
+		wp.index = i + 1
+	}
+
+	if _, detached := flags["d"]; !detached {
+		w.active = p
+	}
+
+	if _, printInfo := flags["P"]; !printInfo {
+		return nil, nil
+	}
+
+	format := flags["F"]
+	if format == "" {
+		format = "#{session_name}:#{window_index}.#{pane_index}"
+	}
+
+	return fakeLines([]string{expandFakeFormat(format, p.vars(s, w))}), nil
+}
+
+func (f *fakeTmux) killWindow(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "t")
+
+	s, w, _, err := f.findPane(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeWindow(w)
+	if len(s.windows) == 0 {
+		f.removeSession(s)
+	}
+
+	return nil, nil
+}
+
+// killPane removes the pane and numbers the rest of panes of its window from
+// 1 again, like tmux. Window of its last pane is closed.
+func (f *fakeTmux) killPane(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "t")
+
+	s, w, p, err := f.findPane(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	var panes []*fakePane
+	for _, wp := range w.panes {
+		if wp != p {
+			wp.index = len(panes) + 1
+			panes = append(panes, wp)
+		}
+	}
+	w.panes = panes
+
+	if len(w.panes) == 0 {
+		s.removeWindow(w)
+		if len(s.windows) == 0 {
+			f.removeSession(s)
+		}
+	} else if w.active == p {
+		w.active = w.panes[0]
+	}
+
+	return nil, nil
+}
+
+func (s *fakeSession) removeWindow(w *fakeWindow) {
+	for i, sw := range s.windows {
+		if sw == w {
+			s.windows = append(s.windows[:i], s.windows[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *fakeTmux) removeSession(s *fakeSession) {
+	for i, fs := range f.sessions {
+		if fs == s {
+			f.sessions = append(f.sessions[:i], f.sessions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *fakeTmux) selectPane(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "tT")
+
+	_, _, p, err := f.findPane(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	if title, ok := flags["T"]; ok {
+		p.title = title
+	}
+
+	return nil, nil
+}
+
+func (f *fakeTmux) sendKeys(args []string) ([]byte, error) {
+	flags, rest := parseFakeArgs(args, "t")
+
+	_, _, p, err := f.findPane(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = append(p.keys, strings.Join(rest, " "))
+
+	// A command typed into an idle shell becomes the running program.
+	if len(rest) > 1 && rest[len(rest)-1] == "Enter" && isShell(p.command) {
+		if fields := strings.Fields(rest[0]); len(fields) > 0 {
+			p.command = fields[0]
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *fakeTmux) capturePane(args []string) ([]byte, error) {
+	flags, _ := parseFakeArgs(args, "tS")
+
+	_, _, p, err := f.findPane(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(p.content), nil
+}
+
+// displayMessage supports only printing format for target with -p.
+func (f *fakeTmux) displayMessage(args []string) ([]byte, error) {
+	flags, rest := parseFakeArgs(args, "t")
+
+	if _, ok := flags["p"]; !ok || len(rest) == 0 {
+		return nil, fmt.Errorf("fake tmux: display-message needs -p and format")
+	}
+
+	s, w, p, err := f.findPane(flags["t"])
+	if err != nil {
+		return nil, err
+	}
+
+	return fakeLines([]string{expandFakeFormat(rest[0], p.vars(s, w))}), nil
+}
+
+// findSession matches session name like tmux: name prefixed with = must match
+// exactly, otherwise a session whose name starts with name is accepted too.
+func (f *fakeTmux) findSession(name string) (*fakeSession, error) {
+	name = strings.TrimSuffix(name, ":")
+	exact, ok := strings.CutPrefix(name, "=")
+
+	for _, s := range f.sessions {
+		if s.name == exact {
+			return s, nil
+		}
+	}
+
+	if !ok {
+		var found []*fakeSession
+		for _, s := range f.sessions {
+			if strings.HasPrefix(s.name, name) {
+				found = append(found, s)
+			}
+		}
+
+		if len(found) == 1 {
+			return found[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("fake tmux: can't find session: %s", name)
+}
+
+func (f *fakeTmux) findWindow(target string) (*fakeWindow, error) {
+	if strings.HasPrefix(target, "%") {
+		_, w, _, err := f.findPane(target)
+		return w, err
+	}
+
+	sessionName, windowIndex, _ := splitFakeTarget(target)
+
+	s, err := f.findSession(sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.findWindow(windowIndex)
+}
+
+// findPane returns pane of target together with its window and session.
+// Target is a pane id, like %3, or session:window.pane. Without pane the
+// active pane of the window is returned, as in tmux.
+func (f *fakeTmux) findPane(target string) (*fakeSession, *fakeWindow, *fakePane, error) {
+	if strings.HasPrefix(target, "%") {
+		for _, s := range f.sessions {
+			for _, w := range s.windows {
+				for _, p := range w.panes {
+					if p.id == target {
+						return s, w, p, nil
+					}
+				}
+			}
+		}
+
+		return nil, nil, nil, fmt.Errorf("fake tmux: can't find pane: %s", target)
+	}
+
+	sessionName, _, paneIndex := splitFakeTarget(target)
+
+	s, err := f.findSession(sessionName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	w, err := f.findWindow(target)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if paneIndex == "" {
+		return s, w, w.active, nil
+	}
+
+	for _, p := range w.panes {
+		if strconv.Itoa(p.index) == paneIndex {
+			return s, w, p, nil
+		}
+	}
+
+	return nil, nil, nil, fmt.Errorf("fake tmux: can't find pane: %s", target)
+}
+
+func (s *fakeSession) findWindow(index string) (*fakeWindow, error) {
+	if index == "" {
+		return s.windows[0], nil
+	}
+
+	for _, w := range s.windows {
+		if strconv.Itoa(w.index) == index {
+			return w, nil
+		}
+	}
+
+	return nil, fmt.Errorf("fake tmux: can't find window: %s:%s", s.name, index)
+}
+
+func (s *fakeSession) vars() map[string]string {
+	return map[string]string{
+		"session_created": strconv.FormatInt(s.created, 10),
+		"session_name":    s.name,
+		"session_windows": strconv.Itoa(len(s.windows)),
+	}
+}
+
+func (w *fakeWindow) vars(s *fakeSession) map[string]string {
+	vars := s.vars()
+	vars["window_index"] = strconv.Itoa(w.index)
+	vars["window_name"] = w.name
+	vars["window_layout"] = w.layout
+	vars["window_panes"] = strconv.Itoa(len(w.panes))
+
+	return vars
+}
+
+func (p *fakePane) vars(s *fakeSession, w *fakeWindow) map[string]string {
+	vars := w.vars(s)
+	vars["pane_id"] = p.id
+	vars["pane_index"] = strconv.Itoa(p.index)
+	vars["pane_active"] = "0"
+	if w.active == p {
+		vars["pane_active"] = "1"
+	}
+	vars["pane_title"] = p.title
+	vars["pane_current_path"] = p.path
+	vars["pane_current_command"] = p.command
+	vars["pane_pid"] = strconv.Itoa(p.pid)
+
+	return vars
+}
+
+func (f *fakeTmux) newWindowWithPane(index int, name, path string) *fakeWindow {
+	p := f.newPane(1, path)
+
+	return &fakeWindow{
+		index:  index,
+		name:   name,
+		layout: fakeDefaultLayout,
+		panes:  []*fakePane{p},
+		active: p,
+	}
+}
+
+func (f *fakeTmux) newPane(index int, path string) *fakePane {
+	f.pid++
+	f.paneID++
+
+	return &fakePane{
+		id:      fmt.Sprintf("%%%d", f.paneID),
+		index:   index,
+		path:    path,
+		command: fakeDefaultCommand,
+		pid:     f.pid,
+	}
+}
+
+// parseFakeArgs splits tmux arguments into flags and positional arguments.
+// Flags listed in withValue consume the following argument as their value,
+// all other flags are stored with an empty value.
+func parseFakeArgs(args []string, withValue string) (map[string]string, []string) {
+	flags := make(map[string]string)
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) != 2 || arg[0] != '-' {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		flag := arg[1:]
+		if strings.Contains(withValue, flag) && i+1 < len(args) {
+			flags[flag] = args[i+1]
+			i++
+			continue
+		}
+
+		flags[flag] = ""
+	}
+
+	return flags, rest
+}
+
+// splitFakeTarget splits target in form of session:window.pane.
+func splitFakeTarget(target string) (string, string, string) {
+	session, window, _ := strings.Cut(target, ":")
+	window, pane, _ := strings.Cut(window, ".")
+
+	return session, window, pane
+}
+
+// expandFakeFormat replaces every #{variable} in format with its value.
+func expandFakeFormat(format string, vars map[string]string) string {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(format, "#{")
+		if start == -1 {
+			b.WriteString(format)
+			break
+		}
+
+		end := strings.Index(format[start:], "}")
+		if end == -1 {
+			b.WriteString(format)
+			break
+		}
+
+		b.WriteString(format[:start])
+		b.WriteString(vars[format[start+2:start+end]])
+		format = format[start+end+1:]
+	}
+
+	return b.String()
+}
+
+func fakeLines(lines []string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+// tmux puts new pane right after the split pane and the panes behind it move
+// up, checked with tmux 3.3a.
+func TestFakeSplitWindowInsertsAfterTarget(t *testing.T) {
+	f := newFakeTmux()
+
+	commands := [][]string{
+		{"new-session", "-d", "-s", "s", "-c", "/tmp"},
+		{"split-window", "-d", "-t", "s:1", "-c", "/usr"},
+		{"split-window", "-d", "-t", "s:1", "-c", "/etc"},
+	}
+	for _, c := range commands {
+		if err := f.Run(c...); err != nil {
+			t.Fatalf("%v error = %v", c, err)
+		}
+	}
+
+	out, err := f.Output("split-window", "-d", "-P", "-F", "#{pane_id} #{pane_index}", "-t", "s:1.3", "-c", "/var")
+	if err != nil {
+		t.Fatalf("split-window error = %v", err)
+	}
+
+	if got := strings.TrimSpace(string(out)); got != "%4 4" {
+		t.Errorf("split-window -P = %q, want %%4 4", got)
+	}
+
+	out, _ = f.Output("list-panes", "-t", "s:1", "-F", "#{pane_index} #{pane_id} #{pane_active} #{pane_current_path}")
+	want := "1 %1 1 /tmp\n2 %3 0 /etc\n3 %2 0 /usr\n4 %4 0 /var\n"
+	if string(out) != want {
+		t.Errorf("list-panes =\n%s\nwant\n%s", out, want)
+	}
+}
+
+// Panes behind the killed one move down, the window of the last pane closes.
+func TestFakeKillPaneRenumbersPanes(t *testing.T) {
+	f := newFakeTmux()
+
+	commands := [][]string{
+		{"new-session", "-d", "-s", "s", "-c", "/tmp"},
+		{"split-window", "-d", "-t", "s:1.1", "-c", "/usr"},
+		{"split-window", "-d", "-t", "s:1.2", "-c", "/etc"},
+		{"new-window", "-t", "s:2", "-c", "/var"},
+		{"kill-pane", "-t", "s:1.2"},
+		{"kill-pane", "-t", "s:2.1"},
+	}
+	for _, c := range commands {
+		if err := f.Run(c...); err != nil {
+			t.Fatalf("%v error = %v", c, err)
+		}
+	}
+
+	out, _ := f.Output("list-panes", "-s", "-t", "s", "-F", "#{window_index}.#{pane_index} #{pane_current_path}")
+	want := "1.1 /tmp\n1.2 /etc\n"
+	if string(out) != want {
+		t.Errorf("list-panes =\n%s\nwant\n%s", out, want)
+	}
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+)
+
+// sessionFilter selects sessions by name. Patterns are globs as understood
+// by path.Match, e.g. `work-*`. Without include patterns every session not
+// excluded is selected.
+type sessionFilter struct {
+	include []string
+	exclude []string
+}
+
+// newSessionFilter builds filter from session names given as arguments and
+// comma separated include and exclude patterns.
+func newSessionFilter(names []string, include, exclude string) (sessionFilter, error) {
+	f := sessionFilter{
+		include: append(append([]string(nil), names...), splitList(include)...),
+		exclude: splitList(exclude),
+	}
+
+	for _, pattern := range append(append([]string(nil), f.include...), f.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return f, fmt.Errorf("Invalid session pattern: %s \n", pattern)
+		}
+	}
+
+	return f, nil
+}
+
+func (f sessionFilter) isEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+func (f sessionFilter) match(name string) bool {
+	for _, pattern := range f.exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for _, pattern := range f.include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply returns sessions selected by f. Include pattern matching no session
+// is an error, so a mistyped name is not silently ignored.
+func (f sessionFilter) apply(sessions []tSession) ([]tSession, error) {
+	for _, pattern := range f.include {
+		found := false
+		for _, s := range sessions {
+			if ok, _ := path.Match(pattern, s.Name); ok {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("No session matches: %s \n", pattern)
+		}
+	}
+
+	var selected []tSession
+	for _, s := range sessions {
+		if f.match(s.Name) {
+			selected = append(selected, s)
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package cli
+
+// lockPath is a no-op on platforms without flock. Writes are still atomic,
+// only concurrent writers are not serialized.
+func lockPath(path string) (func(), error) {
+	return func() {}, nil
+}
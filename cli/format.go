@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileFormat is the on-disk encoding of tmxu files. JSON struct tags are the
+// only mapping between files and structs: YAML and TOML documents are
+// converted to JSON when decoding and from JSON when encoding.
+type fileFormat string
+
+const (
+	formatJSON fileFormat = "json"
+	formatYAML fileFormat = "yaml"
+	formatTOML fileFormat = "toml"
+)
+
+var formatExtensions = map[string]fileFormat{
+	".json": formatJSON,
+	".yaml": formatYAML,
+	".yml":  formatYAML,
+	".toml": formatTOML,
+}
+
+// templateExtensions lists extensions in order of lookup when the template
+// is referenced by name only.
+var templateExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// supportedExtensions lists extensions of templateExtensions for messages.
+func supportedExtensions() string {
+	last := len(templateExtensions) - 1
+	return strings.Join(templateExtensions[:last], ", ") + " or " + templateExtensions[last]
+}
+
+func parseFileFormat(format string) (fileFormat, error) {
+	switch f := fileFormat(strings.ToLower(format)); f {
+	case formatJSON, formatYAML, formatTOML:
+		return f, nil
+	case "yml":
+		return formatYAML, nil
+	}
+
+	return "", fmt.Errorf("Unsupported format: %s. Use json, yaml or toml \n", format)
+}
+
+// formatFromPath returns format of the file based on its extension.
+func formatFromPath(path string) (fileFormat, bool) {
+	f, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]
+	return f, ok
+}
+
+func (f fileFormat) extension() string {
+	return "." + string(f)
+}
+
+// decodeFile decodes data in format f into v.
+func decodeFile(data []byte, f fileFormat, v any) error {
+	if f == formatJSON {
+		return json.Unmarshal(data, v)
+	}
+
+	var doc any
+	switch f {
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	case formatTOML:
+		var table map[string]any
+		if err := toml.Unmarshal(data, &table); err != nil {
+			return err
+		}
+
+		doc = table
+	default:
+		return fmt.Errorf("unsupported format: %s", f)
+	}
+
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(j, v)
+}
+
+// encodeFile encodes v in format f.
+func encodeFile(v any, f fileFormat) ([]byte, error) {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch f {
+	case formatJSON:
+		return j, nil
+	case formatYAML:
+		// JSON is valid YAML, decoding it into a node keeps order of keys.
+		var node yaml.Node
+		if err := yaml.Unmarshal(j, &node); err != nil {
+			return nil, err
+		}
+
+		resetYAMLStyle(&node)
+
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return nil, err
+		}
+
+		return b.Bytes(), enc.Close()
+	case formatTOML:
+		doc, err := decodeJSONDocument(j)
+		if err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if err := toml.NewEncoder(&b).Encode(doc); err != nil {
+			return nil, err
+		}
+
+		return b.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported format: %s", f)
+}
+
+// resetYAMLStyle switches node decoded from JSON to block style without
+// quoted strings.
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		resetYAMLStyle(n)
+	}
+}
+
+// decodeJSONDocument decodes JSON into generic document keeping integers as
+// int64, so TOML encoder does not turn them into floats. Null values are
+// dropped as TOML cannot express them.
+func decodeJSONDocument(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return normalizeJSONValue(doc), nil
+}
+
+func normalizeJSONValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if val == nil {
+				delete(v, k)
+				continue
+			}
+
+			v[k] = normalizeJSONValue(val)
+		}
+	case []any:
+		for i := range v {
+			v[i] = normalizeJSONValue(v[i])
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+		return f
+	}
+
+	return v
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	importTmuxinator = "tmuxinator"
+	importTmuxp      = "tmuxp"
+)
+
+// importReport collects keys of imported config which tmxu cannot express.
+type importReport struct {
+	unsupported []string
+}
+
+func (r *importReport) skip(path string) {
+	r.unsupported = append(r.unsupported, path)
+}
+
+// skipUnknown reports every key of m which is not listed in known.
+func (r *importReport) skipUnknown(prefix string, m map[string]any, known ...string) {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !contains(known, k) {
+			r.skip(prefix + k)
+		}
+	}
+}
+
+// importTemplateFile parses tmuxinator or tmuxp project file into template.
+// With empty kind the kind is detected from keys of the file.
+func importTemplateFile(filePath, kind string) (tTemplate, importReport, error) {
+	var report importReport
+
+	format, ok := formatFromPath(filePath)
+	if !ok {
+		return tTemplate{}, report, fmt.Errorf("Unsupported file: %s. Use %s \n", filePath, supportedExtensions())
+	}
+
+	out, err := os.ReadFile(filePath)
+	if err != nil {
+		return tTemplate{}, report, fmt.Errorf("Unable to read file at path: %s \n", filePath)
+	}
+
+	var doc map[string]any
+	if err := decodeFile(out, format, &doc); err != nil {
+		return tTemplate{}, report, fmt.Errorf("Cannot unmarshal project file: %s \n", filePath)
+	}
+
+	if kind == "" {
+		kind = importTmuxinator
+		if _, ok := doc["session_name"]; ok {
+			kind = importTmuxp
+		}
+	}
+
+	var t tTemplate
+	switch kind {
+	case importTmuxinator:
+		t, err = importTmuxinatorProject(doc, &report)
+	case importTmuxp:
+		t, err = importTmuxpProject(doc, &report)
+	default:
+		return tTemplate{}, report, fmt.Errorf("Unsupported project kind: %s. Use tmuxinator or tmuxp \n", kind)
+	}
+
+	// Other tools have no placeholders, braces in commands are meant literally.
+	return literalTemplate(t), report, err
+}
+
+// importTmuxinatorProject converts tmuxinator project:
+// https://github.com/tmuxinator/tmuxinator#project-configuration
+func importTmuxinatorProject(doc map[string]any, report *importReport) (tTemplate, error) {
+	report.skipUnknown("", doc,
+		"name", "project_name", "root", "project_root", "windows", "tabs",
+		"pre", "pre_window", "pre_tab", "on_project_start", "on_project_first_start", "post",
+	)
+
+	t := tTemplate{
+		Name: firstString(doc["name"], doc["project_name"]),
+		Root: firstString(doc["root"], doc["project_root"]),
+		Pre:  importCommands(doc["on_project_start"], doc["on_project_first_start"], doc["pre"]),
+		Post: importCommands(doc["post"]),
+	}
+
+	preWindow := importCommands(doc["pre_window"], doc["pre_tab"])
+
+	windows, _ := firstValue(doc["windows"], doc["tabs"]).([]any)
+	if len(windows) == 0 {
+		return t, fmt.Errorf("Project has no windows \n")
+	}
+
+	for i, w := range windows {
+		entry, ok := w.(map[string]any)
+		if !ok || len(entry) != 1 {
+			return t, fmt.Errorf("Invalid window definition at position: %d \n", i+1)
+		}
+
+		for name, value := range entry {
+			prefix := fmt.Sprintf("windows[%d].%s.", i, name)
+			window := tWindow{
+				Order:    int16(i + 1),
+				Name:     name,
+				Commands: preWindow,
+			}
+
+			dir := "."
+			var panes []any
+
+			switch value := value.(type) {
+			case map[string]any:
+				report.skipUnknown(prefix, value, "layout", "panes", "root", "pre")
+
+				window.Layout, _ = value["layout"].(string)
+				window.Commands = append(append([]string(nil), preWindow...), importCommands(value["pre"])...)
+				if root, ok := value["root"].(string); ok {
+					dir = importPath(root, t.Root)
+				}
+
+				panes, _ = value["panes"].([]any)
+			default:
+				// Window with a single pane running given commands.
+				panes = []any{value}
+			}
+
+			if len(panes) == 0 {
+				panes = []any{nil}
+			}
+
+			for j, p := range panes {
+				pane := tPane{Order: int16(j + 1), Path: dir}
+
+				if named, ok := p.(map[string]any); ok && len(named) == 1 {
+					for title, commands := range named {
+						pane.Name = title
+						pane.Commands = importCommands(commands)
+					}
+				} else {
+					pane.Commands = importCommands(p)
+				}
+
+				window.Panes = append(window.Panes, pane)
+			}
+
+			t.Windows = append(t.Windows, window)
+		}
+	}
+
+	return t, nil
+}
+
+// importTmuxpProject converts tmuxp workspace:
+// https://tmuxp.git-pull.com/configuration/
+func importTmuxpProject(doc map[string]any, report *importReport) (tTemplate, error) {
+	report.skipUnknown("", doc,
+		"session_name", "start_directory", "before_script", "shell_command_before", "windows",
+	)
+
+	t := tTemplate{
+		Root: firstString(doc["start_directory"]),
+		Name: firstString(doc["session_name"]),
+		Pre:  importCommands(doc["before_script"]),
+	}
+
+	before := importCommands(doc["shell_command_before"])
+
+	windows, _ := doc["windows"].([]any)
+	if len(windows) == 0 {
+		return t, fmt.Errorf("Workspace has no windows \n")
+	}
+
+	for i, w := range windows {
+		value, ok := w.(map[string]any)
+		if !ok {
+			return t, fmt.Errorf("Invalid window definition at position: %d \n", i+1)
+		}
+
+		prefix := fmt.Sprintf("windows[%d].", i)
+		report.skipUnknown(prefix, value,
+			"window_name", "layout", "start_directory", "shell_command_before", "panes",
+		)
+
+		window := tWindow{
+			Order:    int16(i + 1),
+			Name:     firstString(value["window_name"]),
+			Commands: append(append([]string(nil), before...), importCommands(value["shell_command_before"])...),
+		}
+		window.Layout, _ = value["layout"].(string)
+
+		dir := "."
+		if d, ok := value["start_directory"].(string); ok {
+			dir = importPath(d, t.Root)
+		}
+
+		panes, _ := value["panes"].([]any)
+		if len(panes) == 0 {
+			panes = []any{nil}
+		}
+
+		for j, p := range panes {
+			pane := tPane{Order: int16(j + 1), Path: dir}
+
+			if m, ok := p.(map[string]any); ok {
+				report.skipUnknown(fmt.Sprintf("%spanes[%d].", prefix, j), m, "shell_command", "start_directory")
+
+				pane.Commands = importCommands(m["shell_command"])
+				if d, ok := m["start_directory"].(string); ok {
+					// Pane directory is relative to directory of its window.
+					if !filepath.IsAbs(d) && !strings.HasPrefix(d, "~") {
+						d = filepath.Join(dir, d)
+					}
+
+					pane.Path = importPath(d, t.Root)
+				}
+			} else {
+				pane.Commands = importCommands(p)
+			}
+
+			window.Panes = append(window.Panes, pane)
+		}
+
+		t.Windows = append(t.Windows, window)
+	}
+
+	return t, nil
+}
+
+// importPath returns path relative to project root when it is inside of it.
+func importPath(path, root string) string {
+	if root == "" || !(filepath.IsAbs(path) || strings.HasPrefix(path, "~")) {
+		return filepath.Clean(path)
+	}
+
+	return relativePanePath(path, root)
+}
+
+// importCommands flattens commands defined as string, list of strings or
+// tmuxp list of {cmd: ...} objects.
+func importCommands(values ...any) []string {
+	var commands []string
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			commands = append(commands, importCommands(v...)...)
+		case map[string]any:
+			commands = append(commands, importCommands(v["cmd"])...)
+		default:
+			if s := scalarString(v); s != "" {
+				commands = append(commands, s)
+			}
+		}
+	}
+
+	return commands
+}
+
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	return ""
+}
+
+func firstValue(values ...any) any {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func firstString(values ...any) string {
+	for _, v := range values {
+		if s := scalarString(v); s != "" {
+			return s
+		}
+	}
+
+	return ""
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
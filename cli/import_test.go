@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportTemplateFileUnsupported(t *testing.T) {
+	_, _, err := importTemplateFile("project.txt", "")
+	if err == nil {
+		t.Fatal("importTemplateFile() error = nil, want error for unsupported file")
+	}
+
+	for _, ext := range templateExtensions {
+		if !strings.Contains(err.Error(), ext) {
+			t.Errorf("error %q does not mention %s", err, ext)
+		}
+	}
+}
@@ -28,26 +28,47 @@ func interactiveMenu(items []menuItem) (menuItem, error) {
 	fmt.Print("\033[?25l")
 	defer fmt.Print("\033[?25h")
 
+	border := colorize(" ──────────────────────────────────────────────── ", cfg.Theme.Border)
+	hint := fmt.Sprintf(" Use ↑/↓ to navigate, Enter to select, %s to quit", menuKeyName(cfg.Menu.Quit, "Esc"))
+	if cfg.Menu.Up != "" && cfg.Menu.Down != "" {
+		hint = fmt.Sprintf(" Use ↑/↓ or %s/%s to navigate, Enter to select, %s to quit", cfg.Menu.Up, cfg.Menu.Down, menuKeyName(cfg.Menu.Quit, "Esc"))
+	}
+
 	for {
 		fmt.Printf("\033[H\033[2J")
 		fmt.Printf(" tmux sessions \r\n")
-		fmt.Printf(" ──────────────────────────────────────────────── \r\n\n")
+		fmt.Printf("%s\r\n\n", border)
 
 		for i, item := range items {
 			markSelected := " "
 
+			title := item.Title() + " " + strings.Repeat("·", 25-len(item.Title()))
 			if i == selected {
 				markSelected = ">"
+				title = colorize(title, cfg.Theme.Selected)
 			}
 
-			title := item.Title() + " " + strings.Repeat("·", 25-len(item.Title()))
-			fmt.Printf("  %s %s %s \r\n", markSelected, title, item.Desc())
+			fmt.Printf("  %s %s %s \r\n", markSelected, title, colorize(item.Desc(), cfg.Theme.Desc))
 		}
 
-		fmt.Printf("\n ──────────────────────────────────────────────── \r")
-		fmt.Print("\n Use ↑/↓ to navigate, Enter to select, q to quit\r")
+		fmt.Printf("\n%s\r", border)
+		fmt.Print("\n" + hint + "\r")
 
 		b, _ := reader.ReadByte()
+
+		// Keys from config, unset keys are empty and never match.
+		switch string(b) {
+		case cfg.Menu.Up:
+			selected = Max(selected-1, 0)
+			continue
+		case cfg.Menu.Down:
+			selected = Min(selected+1, itemCount-1)
+			continue
+		case cfg.Menu.Quit:
+			fmt.Print("\033[H\033[2J")
+			return nil, errorAborded
+		}
+
 		switch b {
 		case 27:
 			if reader.Buffered() > 0 {
@@ -68,14 +89,20 @@ func interactiveMenu(items []menuItem) (menuItem, error) {
 		case 13: // Enter
 			fmt.Print("\033[H\033[2J")
 			return items[selected], nil
-		case 'q':
-			fmt.Print("\033[H\033[2J")
-			return nil, errorAborded
 		}
 	}
 }
 
-func sessionsToMenuItems(sessions []string) []menuItem {
+// menuKeyName returns key for the menu hint, or fallback when key is unset.
+func menuKeyName(key, fallback string) string {
+	if key == "" {
+		return fallback
+	}
+
+	return key
+}
+
+func sessionsToMenuItems(sessions []tmuxRecord) []menuItem {
 	var items []menuItem
 
 	for _, s := range sessions {
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// process is a program started by the shell of a pane.
+type process struct {
+	pid  string
+	args []string
+	// foreground is set when the process group of the process is the one
+	// in the foreground of its terminal.
+	foreground bool
+}
+
+// processTable maps parent pid to its child processes.
+type processTable map[string][]process
+
+// listProcesses returns process table of the system. It is a variable so it
+// can be replaced together with the tmux runner.
+var listProcesses = func() (processTable, error) {
+	output, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "pgid=", "-o", "tpgid=", "-o", "args=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProcessTable(string(output), procArgs), nil
+}
+
+// parseProcessTable parses output of ps. Arguments are read with readArgs,
+// as ps joins them with spaces. When readArgs returns nil the ps output is
+// split at spaces instead.
+func parseProcessTable(output string, readArgs func(pid string) []string) processTable {
+	procs := make(processTable)
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		pid, ppid, pgid, tpgid := fields[0], fields[1], fields[2], fields[3]
+
+		args := readArgs(pid)
+		if args == nil {
+			args = fields[4:]
+		}
+
+		procs[ppid] = append(procs[ppid], process{pid: pid, args: args, foreground: pgid == tpgid})
+	}
+
+	return procs
+}
+
+// procArgs reads arguments of process from /proc, which keeps arguments
+// containing spaces intact. Returns nil on systems without /proc.
+func procArgs(pid string) []string {
+	data, err := os.ReadFile(filepath.Join("/proc", pid, "cmdline"))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+}
+
+// commandLine returns command line of the program running in the pane with
+// given pid, with arguments quoted for the shell. A child in the foreground
+// of the pane wins over background jobs. Falls back to command when it
+// cannot be found.
+func (pt processTable) commandLine(pid, command string) string {
+	children := pt[pid]
+	if len(children) == 0 {
+		return command
+	}
+
+	p := children[0]
+	for _, c := range children {
+		if c.foreground {
+			p = c
+			break
+		}
+	}
+
+	quoted := make([]string, len(p.args))
+	for i, a := range p.args {
+		quoted[i] = quoteArg(a)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+var shells = []string{"sh", "bash", "zsh", "fish", "dash", "ksh", "tcsh", "csh", "nu"}
+
+func isShell(command string) bool {
+	command = strings.TrimPrefix(filepath.Base(command), "-")
+
+	for _, s := range shells {
+		if s == command {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runHook runs shell command in dir with output attached to the terminal.
+// Name of the session is available as $TMXU_SESSION. It is a variable so
+// dry-run can print hooks instead of running them.
+var runHook = func(command, dir, sessionName string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "TMXU_SESSION="+sessionName)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// defaultAllowedCommands are programs relaunched by restore-sessions.
+var defaultAllowedCommands = []string{
+	"vi", "vim", "nvim", "nano", "emacs", "hx",
+	"less", "more", "man", "tail -f", "tail -F", "watch",
+	"htop", "top", "btop",
+	"ssh", "mosh",
+	"psql", "mysql", "redis-cli", "sqlite3",
+}
+
+// commandPolicy decides which captured commands are relaunched on restore.
+// Entries match either the program name (`vim`) or the beginning of the
+// command line (`tail -f`). Deny list wins over allow list.
+type commandPolicy struct {
+	allow []string
+	deny  []string
+}
+
+func newCommandPolicy(allow, deny string) commandPolicy {
+	return commandPolicy{
+		allow: append(splitList(allow), defaultAllowedCommands...),
+		deny:  splitList(deny),
+	}
+}
+
+func (p commandPolicy) allowed(commandLine string) bool {
+	if commandLine == "" {
+		return false
+	}
+
+	for _, d := range p.deny {
+		if matchCommand(d, commandLine) {
+			return false
+		}
+	}
+
+	for _, a := range p.allow {
+		if matchCommand(a, commandLine) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchCommand(entry, commandLine string) bool {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return false
+	}
+
+	fields[0] = filepath.Base(fields[0])
+	line := strings.Join(fields, " ")
+
+	return entry == fields[0] || entry == line || strings.HasPrefix(line, entry+" ")
+}
+
+// splitList splits comma separated list skipping empty values.
+func splitList(s string) []string {
+	var list []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseProcessTable(t *testing.T) {
+	output := `    1     0     1    -1 /sbin/init
+  100     1   100   200 -zsh
+  150   100   150   200 sleep 100
+  200   100   200   200 vim my notes.md
+  300     1   300    -1 broken
+`
+	// /proc knows arguments of vim only.
+	readArgs := func(pid string) []string {
+		if pid == "200" {
+			return []string{"vim", "my notes.md"}
+		}
+
+		return nil
+	}
+
+	procs := parseProcessTable(output, readArgs)
+
+	want := []process{
+		{pid: "150", args: []string{"sleep", "100"}},
+		{pid: "200", args: []string{"vim", "my notes.md"}, foreground: true},
+	}
+	if !reflect.DeepEqual(procs["100"], want) {
+		t.Errorf("children of 100 = %+v, want %+v", procs["100"], want)
+	}
+
+	if len(procs["1"]) != 2 {
+		t.Errorf("children of 1 = %+v, want 2", procs["1"])
+	}
+}
+
+func TestProcessTableCommandLine(t *testing.T) {
+	procs := processTable{
+		"100": {
+			{pid: "150", args: []string{"sleep", "100"}},
+			{pid: "200", args: []string{"vim", "my notes.md", "it's"}, foreground: true},
+		},
+		"300": {{pid: "301", args: []string{"tail", "-f", "/var/log/app.log"}}},
+	}
+
+	tests := []struct {
+		pid  string
+		want string
+	}{
+		{"100", `vim 'my notes.md' 'it'\''s'`},
+		{"300", "tail -f /var/log/app.log"},
+		{"400", "htop"},
+	}
+
+	for _, tt := range tests {
+		if got := procs.commandLine(tt.pid, "htop"); got != tt.want {
+			t.Errorf("commandLine(%s) = %q, want %q", tt.pid, got, tt.want)
+		}
+	}
+}
+
+func TestProcArgs(t *testing.T) {
+	args := procArgs(strconv.Itoa(os.Getpid()))
+	if args == nil {
+		t.Skip("no /proc on this system")
+	}
+
+	if !reflect.DeepEqual(args, os.Args) {
+		t.Errorf("procArgs() = %q, want %q", args, os.Args)
+	}
+}
+
+func TestCommandPolicyQuotedArguments(t *testing.T) {
+	p := newCommandPolicy("", "")
+
+	if !p.allowed(`vim 'my notes.md'`) {
+		t.Error("vim with quoted argument is not allowed")
+	}
+
+	if p.allowed(`make 'tail -f'`) {
+		t.Error("make is allowed")
+	}
+}
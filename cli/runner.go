@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// TmuxRunner executes tmux commands. Every call to tmux goes through it, so
+// the real binary can be swapped for an in-memory backend such as fakeTmux.
+type TmuxRunner interface {
+	// Run executes tmux command and discards its output.
+	Run(args ...string) error
+	// Output executes tmux command and returns its standard output.
+	Output(args ...string) ([]byte, error)
+	// Interactive executes tmux command attached to the current terminal.
+	Interactive(args ...string) error
+}
+
+// runner is the backend used by all tmux helpers.
+var runner TmuxRunner = execRunner{}
+
+// tmuxServer selects tmux server by socket name (tmux -L) or socket path
+// (tmux -S). Zero value is the default server.
+type tmuxServer struct {
+	SocketName string `json:"socketName,omitempty"`
+	SocketPath string `json:"socketPath,omitempty"`
+}
+
+// activeServer is the server selected with global -L/-S flags or config.
+var activeServer tmuxServer
+
+// args returns tmux options selecting the server.
+func (s tmuxServer) args() []string {
+	switch {
+	case s.SocketPath != "":
+		return []string{"-S", s.SocketPath}
+	case s.SocketName != "":
+		return []string{"-L", s.SocketName}
+	}
+
+	return nil
+}
+
+func (s tmuxServer) String() string {
+	if args := s.args(); args != nil {
+		return strings.Join(args, " ")
+	}
+
+	return "default"
+}
+
+// execRunner runs commands against the tmux binary found in PATH.
+type execRunner struct {
+	server tmuxServer
+}
+
+func (r execRunner) Run(args ...string) error {
+	_, err := r.Output(args...)
+	return err
+}
+
+// Output returns message printed by tmux, like "can't find pane: %3", as
+// the error when the command fails.
+func (r execRunner) Output(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := r.command(args)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return output, errors.New(message)
+		}
+	}
+
+	return output, err
+}
+
+func (r execRunner) command(args []string) *exec.Cmd {
+	return exec.Command("tmux", append(r.server.args(), args...)...)
+}
+
+func (r execRunner) Interactive(args ...string) error {
+	cmd := r.command(args)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// schemaVersion is the version of session, snapshot and template files
+// written by this tmxu. Files without version are version 1: session files
+// hold a bare list of sessions and templates a bare template.
+const schemaVersion = 2
+
+// sessionsFile is the envelope of the sessions file and snapshots.
+type sessionsFile struct {
+	SchemaVersion int `json:"schemaVersion"`
+	// Server the sessions were captured from.
+	tmuxServer
+	Sessions []tSession `json:"sessions"`
+}
+
+// templateFile is the envelope of templates. Fields of the template stay at
+// the top level, so templates remain easy to write by hand.
+type templateFile struct {
+	SchemaVersion int `json:"schemaVersion"`
+	tTemplate
+}
+
+// migration upgrades decoded document of version n to version n+1.
+type migration func(doc any) (any, error)
+
+// sessionsMigrations upgrade sessions file and snapshots. Migration at index
+// i upgrades version i+1.
+var sessionsMigrations = []migration{
+	// 1 -> 2: list of sessions is wrapped into versioned envelope.
+	func(doc any) (any, error) {
+		if _, ok := doc.([]any); !ok {
+			return nil, fmt.Errorf("expected list of sessions")
+		}
+
+		return map[string]any{"sessions": doc}, nil
+	},
+}
+
+// templateMigrations upgrade templates. Migration at index i upgrades
+// version i+1.
+var templateMigrations = []migration{
+	// 1 -> 2: only the version is added.
+	func(doc any) (any, error) {
+		if _, ok := doc.(map[string]any); !ok {
+			return nil, fmt.Errorf("expected template")
+		}
+
+		return doc, nil
+	},
+}
+
+// readVersionedFile decodes file in format f into envelope v. File written by
+// older tmxu is migrated and rewritten in place, the original is kept next to
+// it with .bak extension. File written by newer tmxu is an error.
+func readVersionedFile(path string, f fileFormat, migrations []migration, v any) error {
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read file at path: %s \n", path)
+	}
+
+	var doc any
+	if err := decodeFile(out, f, &doc); err != nil {
+		return fmt.Errorf("Cannot unmarshal file: %s \n", path)
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return fmt.Errorf("Invalid schema version in file: %s \n", path)
+	}
+
+	if version > schemaVersion {
+		return fmt.Errorf("File %s was written by newer tmxu (schema version %d, supported %d). Upgrade tmxu to read it \n", path, version, schemaVersion)
+	}
+
+	for i := version; i < schemaVersion; i++ {
+		if doc, err = migrations[i-1](doc); err != nil {
+			return fmt.Errorf("Unable to migrate file %s from schema version %d: %s \n", path, i, err)
+		}
+	}
+
+	if m, ok := doc.(map[string]any); ok {
+		m["schemaVersion"] = schemaVersion
+	}
+
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("Cannot unmarshal file: %s \n", path)
+	}
+
+	if err := json.Unmarshal(j, v); err != nil {
+		return fmt.Errorf("Cannot unmarshal file: %s \n", path)
+	}
+
+	if version == schemaVersion {
+		return nil
+	}
+
+	return rewriteMigratedFile(path, f, out, v)
+}
+
+// rewriteMigratedFile saves v in place of file at path keeping original data
+// as backup. Modification time is kept, as snapshots are ordered by it.
+func rewriteMigratedFile(path string, f fileFormat, original []byte, v any) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read file at path: %s \n", path)
+	}
+
+	out, err := encodeFile(v, f)
+	if err != nil {
+		return fmt.Errorf("Cannot marshal migrated file: %s \n", path)
+	}
+
+	return withConfigLock(func() error {
+		if err := writeFileAtomic(path+".bak", original, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("Unable to back up file at path: %s \n", path)
+		}
+
+		if err := writeFileAtomic(path, out, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("Unable to save migrated file at path: %s \n", path)
+		}
+
+		return os.Chtimes(path, info.ModTime(), info.ModTime())
+	})
+}
+
+// documentVersion returns schema version of decoded document.
+func documentVersion(doc any) (int, error) {
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return 1, nil
+	}
+
+	v, ok := m["schemaVersion"]
+	if !ok {
+		return 1, nil
+	}
+
+	version, ok := v.(float64)
+	if !ok || version < 1 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid schema version: %v", v)
+	}
+
+	return int(version), nil
+}
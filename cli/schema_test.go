@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadVersionedFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		migrations []migration
+		// read decodes the file into a fresh envelope and returns its
+		// schema version and name of the first session or template.
+		read        func(path string, migrations []migration) (int, string, error)
+		wantName    string
+		wantErr     bool
+		wantRewrite bool
+	}{
+		{
+			name:        "legacy sessions list",
+			content:     `[{"order":1,"name":"work","windows":[]}]`,
+			migrations:  sessionsMigrations,
+			read:        readSessionsEnvelope,
+			wantName:    "work",
+			wantRewrite: true,
+		},
+		{
+			name:        "legacy template with TEMP_VALUE",
+			content:     `{"name":"dev","windows":[{"order":1,"name":"code","panes":[{"order":1,"path":"TEMP_VALUE"}]}]}`,
+			migrations:  templateMigrations,
+			read:        readTemplateEnvelope,
+			wantName:    "dev",
+			wantRewrite: true,
+		},
+		{
+			name:       "current sessions file",
+			content:    `{"schemaVersion":2,"sessions":[{"order":1,"name":"work","windows":[]}]}`,
+			migrations: sessionsMigrations,
+			read:       readSessionsEnvelope,
+			wantName:   "work",
+		},
+		{
+			name:       "current template",
+			content:    `{"schemaVersion":2,"name":"dev","windows":[]}`,
+			migrations: templateMigrations,
+			read:       readTemplateEnvelope,
+			wantName:   "dev",
+		},
+		{
+			name:       "newer version",
+			content:    `{"schemaVersion":3,"sessions":[]}`,
+			migrations: sessionsMigrations,
+			read:       readSessionsEnvelope,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid version",
+			content:    `{"schemaVersion":"next","sessions":[]}`,
+			migrations: sessionsMigrations,
+			read:       readSessionsEnvelope,
+			wantErr:    true,
+		},
+		{
+			name:       "legacy file of other kind",
+			content:    `{"name":"dev","windows":[]}`,
+			migrations: sessionsMigrations,
+			read:       readSessionsEnvelope,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMXU_HOME", t.TempDir())
+
+			path := filepath.Join(t.TempDir(), "file.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			version, name, err := tt.read(path, tt.migrations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readVersionedFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && (version != schemaVersion || name != tt.wantName) {
+				t.Errorf("readVersionedFile() = version %d, name %q, want %d, %q", version, name, schemaVersion, tt.wantName)
+			}
+
+			out, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			backup, backupErr := os.ReadFile(path + ".bak")
+
+			if !tt.wantRewrite {
+				if string(out) != tt.content {
+					t.Errorf("file was rewritten:\n%s", out)
+				}
+
+				if !os.IsNotExist(backupErr) {
+					t.Errorf("backup exists, error = %v", backupErr)
+				}
+
+				return
+			}
+
+			if !strings.Contains(string(out), `"schemaVersion": 2`) {
+				t.Errorf("migrated file has no schema version:\n%s", out)
+			}
+
+			if string(backup) != tt.content {
+				t.Errorf("backup = %s, want original content, error = %v", backup, backupErr)
+			}
+
+			// Migrated file reads as current without another rewrite.
+			if _, _, err := tt.read(path, tt.migrations); err != nil {
+				t.Errorf("reading migrated file error = %v", err)
+			}
+		})
+	}
+}
+
+func readSessionsEnvelope(path string, migrations []migration) (int, string, error) {
+	var f sessionsFile
+	if err := readVersionedFile(path, formatJSON, migrations, &f); err != nil {
+		return 0, "", err
+	}
+
+	if len(f.Sessions) == 0 {
+		return f.SchemaVersion, "", nil
+	}
+
+	return f.SchemaVersion, f.Sessions[0].Name, nil
+}
+
+func readTemplateEnvelope(path string, migrations []migration) (int, string, error) {
+	var f templateFile
+	if err := readVersionedFile(path, formatJSON, migrations, &f); err != nil {
+		return 0, "", err
+	}
+
+	return f.SchemaVersion, f.Name, nil
+}
+
+func TestReadLegacyTemplateFile(t *testing.T) {
+	t.Setenv("TMXU_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "dev.json")
+	content := `{"name":"dev","windows":[{"order":1,"name":"code","panes":[{"order":1,"path":"TEMP_VALUE"}]}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := readTemplateFile(path, formatJSON)
+	if err != nil {
+		t.Fatalf("readTemplateFile() error = %v", err)
+	}
+
+	vars, err := newTemplateVars("/srv/app", "app", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Placeholder of older save-template still points at the project root.
+	got, err := expandTemplate(tmpl, vars)
+	if err != nil {
+		t.Fatalf("expandTemplate() error = %v", err)
+	}
+
+	if path := got.Windows[0].Panes[0].Path; path != "/srv/app" {
+		t.Errorf("pane path = %q, want /srv/app", path)
+	}
+}
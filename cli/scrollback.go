@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const scrollbackDir = "scrollback"
+
+// saveScrollbacks captures content of every pane and stores it compressed in
+// scrollback/<snapshot> of the state dir. Panes are updated with paths of their
+// files relative to the scrollback dir.
+func saveScrollbacks(sessions []tSession, snapshot string) error {
+	root, err := getScrollbackDirPath()
+	if err != nil {
+		return fmt.Errorf("Unable to get scrollback dir \n")
+	}
+
+	// Content left from previous save of the same snapshot is outdated.
+	path := filepath.Join(root, snapshot)
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("Unable to clear scrollback dir: %s \n", path)
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("Unable to create scrollback dir: %s \n", path)
+	}
+
+	for i := range sessions {
+		for j := range sessions[i].Windows {
+			for k := range sessions[i].Windows[j].Panes {
+				pane := &sessions[i].Windows[j].Panes[k]
+
+				content, err := CapturePane(pane.target())
+				if err != nil {
+					return fmt.Errorf("Unable to capture content of pane: %s \n", pane.target())
+				}
+
+				// Drop empty lines below the cursor, tmux pads output to pane height.
+				content = append(bytes.TrimRight(content, "\n"), '\n')
+
+				fileName := scrollbackFileName(*pane)
+				if err := writeScrollbackFile(filepath.Join(path, fileName), content); err != nil {
+					return err
+				}
+
+				pane.Scrollback = filepath.Join(snapshot, fileName)
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeScrollbackFile(filePath string, content []byte) error {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return fmt.Errorf("Cannot compress scrollback for file: %s \n", filePath)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("Cannot compress scrollback for file: %s \n", filePath)
+	}
+
+	if err := writeFileAtomic(filePath, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("Cannot save scrollback file at path: %s \n", filePath)
+	}
+
+	return nil
+}
+
+// replayScrollback prints saved content back into restored window panes.
+// Panes without saved content or with missing files are left untouched.
+func replayScrollback(window tWindow) error {
+	path, err := getScrollbackDirPath()
+	if err != nil {
+		return fmt.Errorf("Unable to get scrollback dir \n")
+	}
+
+	for _, pane := range window.Panes {
+		if pane.Scrollback == "" {
+			continue
+		}
+
+		filePath := filepath.Join(path, pane.Scrollback)
+		if _, err := os.Stat(filePath); err != nil {
+			continue
+		}
+
+		// Leading space keeps the command out of shell history.
+		keys := fmt.Sprintf(" clear; gzip -dc %s", shellQuote(filePath))
+		if err := SendKeys(pane.target(), keys); err != nil {
+			return fmt.Errorf("Unable to restore content of pane: %s \n", pane.target())
+		}
+	}
+
+	return nil
+}
+
+func getScrollbackDirPath() (string, error) {
+	path, err := getStateSubdirPath(scrollbackDir)
+	if err != nil {
+		return "", fmt.Errorf("Unable to get scrollback dir \n")
+	}
+
+	return path, nil
+}
+
+// scrollbackFileName returns file name for pane content, e.g. work:2.1.gz
+// for pane 1 in window 2 of session work. The target is escaped, so every
+// pane gets its own file and no name contains a path separator.
+func scrollbackFileName(pane tPane) string {
+	return url.PathEscape(pane.target()) + ".gz"
+}
+
+// shellQuote wraps s in single quotes so it can be safely typed into a shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
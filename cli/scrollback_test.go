@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrollbackFileName(t *testing.T) {
+	seen := make(map[string]string)
+
+	for _, session := range []string{"a b", "a_b", "a.b", "a/b", "a%20b", "work"} {
+		pane := newTestSession(session, []string{"/tmp"}).Windows[0].Panes[0]
+		name := scrollbackFileName(pane)
+
+		if strings.Contains(name, "/") {
+			t.Errorf("scrollbackFileName(%q) = %q, contains path separator", session, name)
+		}
+
+		if other, ok := seen[name]; ok {
+			t.Errorf("sessions %q and %q share scrollback file %q", other, session, name)
+		}
+		seen[name] = session
+	}
+
+	if got := scrollbackFileName(newTestSession("work", nil, []string{"/tmp"}).Windows[1].Panes[0]); got != "work:2.1.gz" {
+		t.Errorf("scrollbackFileName() = %q, want work:2.1.gz", got)
+	}
+}
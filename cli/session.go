@@ -0,0 +1,511 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// captureSessions builds tSession with all windows and panes for every running
+// tmux session.
+func captureSessions() ([]tSession, error) {
+	lp, err := ListAllPanes()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list all tmux sessions \n")
+	}
+
+	// Without process table panes only keep the name of running command.
+	procs, _ := listProcesses()
+
+	return assembleSessions(lp, procs)
+}
+
+// captureSession fills ts with windows and panes of the running session.
+// Command lines of running programs are looked up in procs.
+func captureSession(ts tSession, procs processTable) (tSession, error) {
+	lp, err := ListSessionPanes(ts.Name)
+	if err != nil {
+		return ts, fmt.Errorf("Unable to list windows for session: %s \n", ts.Name)
+	}
+
+	sessions, err := assembleSessions(lp, procs)
+	if err != nil {
+		return ts, err
+	}
+
+	for _, s := range sessions {
+		ts.Windows = append(ts.Windows, s.Windows...)
+	}
+
+	return ts, nil
+}
+
+// assembleSessions builds sessions from panes listed with paneFormat. tmux
+// lists panes of a window, and windows of a session, next to each other.
+// Sessions are ordered by the time they were created.
+func assembleSessions(panes []tmuxRecord, procs processTable) ([]tSession, error) {
+	var tSessions []tSession
+	created := make(map[string]int)
+
+	for _, p := range panes {
+		if n := len(tSessions); n == 0 || tSessions[n-1].Name != p["session_name"] {
+			ts, err := newTSession(p, n+1)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to create tSession: %s \n", ts.Name)
+			}
+
+			created[ts.Name], _ = p.int("session_created")
+			tSessions = append(tSessions, ts)
+		}
+
+		ts := &tSessions[len(tSessions)-1]
+
+		if n := len(ts.Windows); n == 0 || ts.Windows[n-1].SessionWindow != fmt.Sprintf("%s:%s", ts.Name, p["window_index"]) {
+			tw, err := newTWindow(p, ts.Name)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to create tWindow: %s \n", tw.Name)
+			}
+
+			ts.Windows = append(ts.Windows, tw)
+		}
+
+		tw := &ts.Windows[len(ts.Windows)-1]
+
+		tp, err := newTPane(p, tw.SessionName, tw.SessionWindow)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create tPane: %s \n", tp.Name)
+		}
+
+		if tp.Command != "" {
+			tp.CommandLine = procs.commandLine(tp.pid, tp.Command)
+		}
+
+		tw.Panes = append(tw.Panes, tp)
+	}
+
+	sort.SliceStable(tSessions, func(i, j int) bool {
+		return created[tSessions[i].Name] < created[tSessions[j].Name]
+	})
+
+	for i := range tSessions {
+		tSessions[i].Order = int16(i + 1)
+	}
+
+	return tSessions, nil
+}
+
+// conflictStrategy decides what restore does with a saved session whose
+// name is already taken by a running session.
+type conflictStrategy string
+
+const (
+	// conflictSkip leaves the running session untouched.
+	conflictSkip conflictStrategy = "skip"
+	// conflictReplace kills the running session once the saved one is built.
+	conflictReplace conflictStrategy = "replace"
+	// conflictRename restores the saved session as name-2, name-3, ...
+	conflictRename conflictStrategy = "rename"
+	// conflictMerge adds saved windows and panes missing in the running
+	// session.
+	conflictMerge conflictStrategy = "merge"
+)
+
+func parseConflictStrategy(strategy string) (conflictStrategy, error) {
+	switch c := conflictStrategy(strategy); c {
+	case conflictSkip, conflictReplace, conflictRename, conflictMerge:
+		return c, nil
+	}
+
+	return "", fmt.Errorf("Unsupported conflict strategy: %s. Use skip, replace, rename or merge \n", strategy)
+}
+
+type restoreOptions struct {
+	// onConflict handles sessions which already exist. Defaults to skip.
+	onConflict conflictStrategy
+	// commands decides which captured programs are relaunched.
+	commands commandPolicy
+	// dryRun reports sessions as planned. The runner must be swapped with
+	// startDryRun beforehand.
+	dryRun bool
+}
+
+// restoreSessions recreates saved sessions. Every session is restored on its
+// own: a session which fails is removed and the rest are still restored.
+// Sessions which already exist are handled according to opts.onConflict.
+func restoreSessions(sessions []tSession, opts restoreOptions) error {
+	var restored, skipped, failed []string
+
+	for _, s := range sessions {
+		err := restoreSession(s, opts)
+		if errors.Is(err, errorSessionExists) {
+			fmt.Printf("Session already exist, skipped: %s \n", s.Name)
+			skipped = append(skipped, s.Name)
+			continue
+		} else if err != nil {
+			fmt.Printf("Session failed: %s. %s", s.Name, err)
+			failed = append(failed, s.Name)
+			continue
+		}
+
+		restored = append(restored, s.Name)
+	}
+
+	fmt.Printf("Restored: %d, skipped: %d, failed: %d \n", len(restored), len(skipped), len(failed))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Unable to restore sessions: %s \n", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// restoreSession restores s, resolving conflict with running session of the
+// same name. Returns errorSessionExists when the session is skipped.
+func restoreSession(s tSession, opts restoreOptions) error {
+	if exists, _ := HasSession(s.Name); !exists {
+		if err := createSession(s, opts); err != nil {
+			return err
+		}
+
+		reportRestored(opts, "created", s)
+		return nil
+	}
+
+	switch opts.onConflict {
+	case conflictReplace:
+		return replaceSession(s, opts)
+	case conflictRename:
+		renamed := s.withName(unusedSessionName(s.Name))
+		if err := createSession(renamed, opts); err != nil {
+			return err
+		}
+
+		reportRestored(opts, "created", renamed)
+		return nil
+	case conflictMerge:
+		return mergeSession(s, opts)
+	}
+
+	return errorSessionExists
+}
+
+// createSession builds session s, killing it when anything fails. Windows
+// and panes are created with a single batch of tmux commands.
+func createSession(s tSession, opts restoreOptions) error {
+	if err := NewSession(s, false); err != nil {
+		return fmt.Errorf("Unable to create session: %s \n", s.Name)
+	}
+
+	if err := batched(func() error { return buildSession(s, opts) }); err != nil {
+		if kerr := KillSession(s.Name); kerr != nil {
+			return fmt.Errorf("%sUnable to remove partially restored session: %s \n", err, s.Name)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// replaceSession builds s under a temporary name and takes over the name
+// only after it is complete, so the running session is kept if anything fails.
+func replaceSession(s tSession, opts restoreOptions) error {
+	build := s.withName(unusedSessionName(s.Name + "-restore"))
+	if err := createSession(build, opts); err != nil {
+		return err
+	}
+
+	if err := KillSession(s.Name); err != nil {
+		KillSession(build.Name)
+		return fmt.Errorf("Unable to kill session: %s \n", s.Name)
+	}
+
+	if err := RenameSession(build.Name, s.Name); err != nil {
+		return fmt.Errorf("Unable to rename session %s to: %s \n", build.Name, s.Name)
+	}
+
+	reportRestored(opts, "replaced", s)
+	return nil
+}
+
+// mergeSession adds windows of s missing in the running session and panes
+// missing in its windows. Windows are matched by name, new windows are added
+// after the running ones. Nothing running is closed or moved. Missing windows
+// and panes are created with a single batch of tmux commands, when it fails
+// the windows and panes it added are closed again.
+func mergeSession(s tSession, opts restoreOptions) error {
+	running, err := captureSession(tSession{Name: s.Name}, nil)
+	if err != nil {
+		return err
+	}
+
+	next := 1
+	for _, w := range running.Windows {
+		next = Max(next, int(w.Order)+1)
+	}
+
+	added := tSession{Name: s.Name}
+
+	err = batched(func() error {
+		for _, window := range s.Windows {
+			current, ok := findWindowByName(running, window.Name)
+			if !ok {
+				window = window.withOrder(s.Name, int16(next))
+				next++
+
+				if err := buildWindow(window, opts); err != nil {
+					return err
+				}
+
+				added.Windows = append(added.Windows, window)
+				continue
+			}
+
+			if len(window.Panes) <= len(current.Panes) {
+				continue
+			}
+
+			// Split panes keep saved order as tmux numbers panes from 1 in
+			// order of creation.
+			window = window.withOrder(s.Name, current.Order)
+			missing := window
+			missing.Panes = window.Panes[len(current.Panes):]
+
+			for _, pane := range missing.Panes {
+				if err := NewPane(pane); err != nil {
+					return fmt.Errorf("Unable to create pane: %s \n", pane.target())
+				}
+			}
+
+			if err := SetWindowLayout(window); err != nil {
+				return fmt.Errorf("Unable to set layout for window: %s \n", window.SessionWindow)
+			}
+
+			if err := replayScrollback(missing); err != nil {
+				return err
+			}
+
+			if err := relaunchCommands(missing, opts.commands); err != nil {
+				return err
+			}
+
+			added.Windows = append(added.Windows, missing)
+		}
+
+		return nil
+	})
+	if err != nil {
+		if rollbackErr := rollbackMerge(running); rollbackErr != nil {
+			return fmt.Errorf("%sSession %s was merged partially, unable to remove added windows and panes \n", err, s.Name)
+		}
+
+		return err
+	}
+
+	reportRestored(opts, "merged", added)
+	return nil
+}
+
+// rollbackMerge closes windows and panes added to the session since it was
+// captured as running. Panes are added after existing ones of the window, so
+// every pane past their count is a new one.
+func rollbackMerge(running tSession) error {
+	current, err := captureSession(tSession{Name: running.Name}, nil)
+	if err != nil {
+		return err
+	}
+
+	return batched(func() error {
+		for _, window := range current.Windows {
+			before, ok := findWindowByOrder(running, window.Order)
+			if !ok {
+				if err := KillWindow(window); err != nil {
+					return err
+				}
+				continue
+			}
+
+			for i := len(window.Panes) - 1; i >= len(before.Panes); i-- {
+				if err := KillPane(window.Panes[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func findWindowByName(s tSession, name string) (tWindow, bool) {
+	for _, w := range s.Windows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+
+	return tWindow{}, false
+}
+
+// reportRestored prints restored session with number of its windows and
+// panes.
+func reportRestored(opts restoreOptions, action string, s tSession) {
+	if opts.dryRun {
+		action = "would be " + action
+	}
+
+	numberOfPanes := 0
+	for _, window := range s.Windows {
+		numberOfPanes += len(window.Panes)
+	}
+
+	fmt.Printf("Session %s: %s (%d windows) [%d panes] \n", action, s.Name, len(s.Windows), numberOfPanes)
+}
+
+// buildSession creates windows and panes of session s which already exists.
+func buildSession(s tSession, opts restoreOptions) error {
+	for _, window := range s.Windows {
+		if err := buildWindow(window, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildWindow creates window with its panes, layout, scrollback and programs.
+func buildWindow(window tWindow, opts restoreOptions) error {
+	if err := NewWindow(window); err != nil {
+		return fmt.Errorf("Unable to create window: %s \n", window.SessionWindow)
+	}
+
+	for _, pane := range window.Panes {
+		if err := NewPane(pane); err != nil {
+			return fmt.Errorf("Unable to create pane: %s \n", pane.target())
+		}
+	}
+
+	if err := SetWindowLayout(window); err != nil {
+		return fmt.Errorf("Unable to set layout for window: %s \n", window.SessionWindow)
+	}
+
+	if err := replayScrollback(window); err != nil {
+		return err
+	}
+
+	return relaunchCommands(window, opts.commands)
+}
+
+// newSessionFromTemplate creates sessionName based on template t. Placeholders
+// in the template are expanded with path as {{.Root}} and user vars. Pre hooks
+// run in path before the session is created, post hooks once it is ready.
+func newSessionFromTemplate(t tTemplate, sessionName, path string, vars map[string]string) error {
+	data, err := newTemplateVars(path, sessionName, vars)
+	if err != nil {
+		return err
+	}
+
+	t, err = expandTemplate(t, data)
+	if err != nil {
+		return err
+	}
+
+	t.Name = sessionName
+	for i := range t.Windows {
+		window := &t.Windows[i]
+		window.SessionName = sessionName
+		window.SessionWindow = fmt.Sprintf("%s:%d", sessionName, i+1)
+
+		for j := range window.Panes {
+			window.Panes[j].Path = resolvePanePath(window.Panes[j].Path, path)
+			window.Panes[j].SessionName = sessionName
+			window.Panes[j].SessionWindow = window.SessionWindow
+		}
+	}
+
+	if hs, _ := HasSession(sessionName); hs {
+		return fmt.Errorf("Session already exist: %s \n", t.Name)
+	}
+
+	for _, hook := range t.Pre {
+		if err := runHook(hook, path, sessionName); err != nil {
+			return fmt.Errorf("Pre hook failed: %s \n", hook)
+		}
+	}
+
+	err = NewSession(t, false)
+	if errors.Is(err, errorSessionExists) {
+		return fmt.Errorf("Session already exist: %s \n", t.Name)
+	} else if err != nil {
+		return fmt.Errorf("Unable to create session: %s \n", t.Name)
+	}
+
+	for _, window := range t.Windows {
+		if err := NewWindow(window); err != nil {
+			return fmt.Errorf("Unable to create window: %s \n", window.SessionWindow)
+		}
+
+		// Panes are targeted by id, as index of a pane changes when a pane
+		// before it is split.
+		previous, err := PaneID(window.SessionWindow)
+		if err != nil {
+			return fmt.Errorf("Unable to find first pane of window: %s \n", window.SessionWindow)
+		}
+
+		for j := range window.Panes {
+			pane := &window.Panes[j]
+
+			pane.id = previous
+			if j > 0 {
+				if pane.id, err = SplitPane(previous, pane.Path); err != nil {
+					return fmt.Errorf("Unable to create pane: %s \n", pane.Name)
+				}
+			}
+			previous = pane.id
+
+			if err := RenamePane(*pane); err != nil {
+				return err
+			}
+
+			commands := append(append([]string(nil), window.Commands...), pane.Commands...)
+			for _, c := range commands {
+				if err := SendKeys(pane.target(), c); err != nil {
+					return fmt.Errorf("Unable to start command in pane: %s \n", pane.target())
+				}
+			}
+		}
+
+		if window.Layout != "" {
+			if err := SetWindowLayout(window); err != nil {
+				return fmt.Errorf("Unable to set layout for window: %s \n", window.SessionWindow)
+			}
+		}
+
+		if err := relaunchCommands(window, newCommandPolicy("", "")); err != nil {
+			return err
+		}
+	}
+
+	for _, hook := range t.Post {
+		if err := runHook(hook, path, sessionName); err != nil {
+			return fmt.Errorf("Post hook failed: %s \n", hook)
+		}
+	}
+
+	return nil
+}
+
+// relaunchCommands starts captured programs allowed by policy in window panes.
+func relaunchCommands(window tWindow, policy commandPolicy) error {
+	for _, pane := range window.Panes {
+		if !policy.allowed(pane.CommandLine) {
+			continue
+		}
+
+		if err := SendKeys(pane.target(), pane.CommandLine); err != nil {
+			return fmt.Errorf("Unable to start command in pane: %s \n", pane.target())
+		}
+	}
+
+	return nil
+}
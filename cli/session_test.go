@@ -0,0 +1,586 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useFakeTmux makes every tmux call of the test go to a fake seeded with
+// sessions. Files of tmxu are kept in a temporary dir.
+func useFakeTmux(t *testing.T, sessions ...tSession) *fakeTmux {
+	t.Helper()
+
+	f := newFakeTmuxFrom(sessions)
+	prevRunner, prevProcs := runner, listProcesses
+
+	runner = f
+	listProcesses = func() (processTable, error) { return processTable{}, nil }
+	t.Setenv("TMXU_HOME", t.TempDir())
+
+	t.Cleanup(func() {
+		runner, listProcesses = prevRunner, prevProcs
+	})
+
+	return f
+}
+
+// newTestSession returns session name with a window for every list of pane
+// paths. Windows are named w1, w2 and so on.
+func newTestSession(name string, windows ...[]string) tSession {
+	s := tSession{Name: name}
+
+	for i, paths := range windows {
+		w := tWindow{Order: int16(i + 1), Name: fmt.Sprintf("w%d", i+1), Layout: "tiled"}
+		for j, path := range paths {
+			w.Panes = append(w.Panes, tPane{Order: int16(j + 1), Path: path})
+		}
+
+		s.Windows = append(s.Windows, w)
+	}
+
+	return s.withName(name)
+}
+
+// describeSessions renders sessions as "name[window:path,path window:path]"
+// for easy comparison.
+func describeSessions(sessions []tSession) []string {
+	var d []string
+
+	for _, s := range sessions {
+		var windows []string
+		for _, w := range s.Windows {
+			var paths []string
+			for _, p := range w.Panes {
+				paths = append(paths, p.Path)
+			}
+
+			windows = append(windows, w.Name+":"+strings.Join(paths, ","))
+		}
+
+		d = append(d, fmt.Sprintf("%s[%s]", s.Name, strings.Join(windows, " ")))
+	}
+
+	return d
+}
+
+func assertSessions(t *testing.T, want ...string) {
+	t.Helper()
+
+	sessions, err := captureSessions()
+	if err != nil {
+		t.Fatalf("captureSessions() error = %v", err)
+	}
+
+	if got := describeSessions(sessions); !reflect.DeepEqual(got, want) {
+		t.Errorf("sessions = %q, want %q", got, want)
+	}
+}
+
+func TestCaptureSessions(t *testing.T) {
+	useFakeTmux(t,
+		newTestSession("work", []string{"/tmp", "/usr"}, []string{"/etc"}),
+		newTestSession("my notes", []string{"/var/my logs"}),
+	)
+
+	sessions, err := captureSessions()
+	if err != nil {
+		t.Fatalf("captureSessions() error = %v", err)
+	}
+
+	want := []string{"work[w1:/tmp,/usr w2:/etc]", "my notes[w1:/var/my logs]"}
+	if got := describeSessions(sessions); !reflect.DeepEqual(got, want) {
+		t.Errorf("sessions = %q, want %q", got, want)
+	}
+
+	for i, s := range sessions {
+		if int(s.Order) != i+1 {
+			t.Errorf("session %s order = %d, want %d", s.Name, s.Order, i+1)
+		}
+	}
+
+	pane := sessions[0].Windows[0].Panes[1]
+	if pane.SessionWindow != "work:1" || pane.target() != "work:1.2" {
+		t.Errorf("pane target = %s, want work:1.2", pane.target())
+	}
+}
+
+func TestRestoreSessions(t *testing.T) {
+	useFakeTmux(t)
+
+	saved := []tSession{
+		newTestSession("work", []string{"/tmp", "/usr"}, []string{"/etc"}),
+		newTestSession("play", []string{"/var"}),
+	}
+
+	if err := restoreSessions(saved, restoreOptions{onConflict: conflictSkip}); err != nil {
+		t.Fatalf("restoreSessions() error = %v", err)
+	}
+
+	assertSessions(t, "work[w1:/tmp,/usr w2:/etc]", "play[w1:/var]")
+}
+
+func TestRestoreSessionsOnConflict(t *testing.T) {
+	saved := newTestSession("work", []string{"/tmp", "/usr"}, []string{"/etc"})
+	running := newTestSession("work", []string{"/tmp"}, []string{"/home"})
+
+	tests := []struct {
+		strategy conflictStrategy
+		want     []string
+	}{
+		{conflictSkip, []string{"work[w1:/tmp w2:/home]"}},
+		{conflictReplace, []string{"work[w1:/tmp,/usr w2:/etc]"}},
+		{conflictRename, []string{"work[w1:/tmp w2:/home]", "work-2[w1:/tmp,/usr w2:/etc]"}},
+		// Windows are matched by name: w1 gets the missing pane, w2 is
+		// kept as it is.
+		{conflictMerge, []string{"work[w1:/tmp,/usr w2:/home]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			useFakeTmux(t, running)
+
+			if err := restoreSessions([]tSession{saved}, restoreOptions{onConflict: tt.strategy}); err != nil {
+				t.Fatalf("restoreSessions() error = %v", err)
+			}
+
+			assertSessions(t, tt.want...)
+		})
+	}
+}
+
+// tmux matches a session by prefix of its name, a saved "kee" must not be
+// taken for a running "keep".
+func TestRestoreSessionsOnConflictPrefixName(t *testing.T) {
+	saved := newTestSession("kee", []string{"/tmp", "/usr"})
+	running := newTestSession("keep", []string{"/home"})
+
+	for _, strategy := range []conflictStrategy{conflictSkip, conflictReplace, conflictRename, conflictMerge} {
+		t.Run(string(strategy), func(t *testing.T) {
+			useFakeTmux(t, running)
+
+			if err := restoreSessions([]tSession{saved}, restoreOptions{onConflict: strategy}); err != nil {
+				t.Fatalf("restoreSessions() error = %v", err)
+			}
+
+			assertSessions(t, "keep[w1:/home]", "kee[w1:/tmp,/usr]")
+		})
+	}
+}
+
+func TestRestoreSessionsMergeAddsWindows(t *testing.T) {
+	useFakeTmux(t, newTestSession("work", []string{"/tmp"}))
+
+	saved := newTestSession("work", []string{"/tmp"})
+	saved.Windows = append(saved.Windows, tWindow{Name: "logs", Layout: "tiled", Panes: []tPane{{Order: 1, Path: "/var/log"}}})
+	saved = saved.withName("work")
+
+	if err := restoreSessions([]tSession{saved}, restoreOptions{onConflict: conflictMerge}); err != nil {
+		t.Fatalf("restoreSessions() error = %v", err)
+	}
+
+	assertSessions(t, "work[w1:/tmp logs:/var/log]")
+}
+
+func TestRestoreSessionsMergeRollsBack(t *testing.T) {
+	f := useFakeTmux(t, newTestSession("work", []string{"/tmp"}))
+
+	saved := newTestSession("work", []string{"/tmp", "/usr"})
+	saved.Windows = append(saved.Windows,
+		tWindow{Name: "logs", Layout: "tiled", Panes: []tPane{{Order: 1, Path: "/var/log"}}},
+		tWindow{Name: "build", Layout: "tiled", Panes: []tPane{{Order: 1, Path: "/etc"}}},
+	)
+	saved = saved.withName("work")
+
+	// Creating the second new window fails after the pane and logs window
+	// were added.
+	f.failTarget = "=work:3"
+
+	if err := restoreSessions([]tSession{saved}, restoreOptions{onConflict: conflictMerge}); err == nil {
+		t.Fatal("restoreSessions() error = nil, want error for failed merge")
+	}
+
+	f.failTarget = ""
+	assertSessions(t, "work[w1:/tmp]")
+}
+
+func TestRestoreSessionsRemovesFailedSession(t *testing.T) {
+	f := useFakeTmux(t)
+
+	broken := newTestSession("broken", []string{"/tmp"})
+	broken.Windows[0].Panes[0].SessionWindow = "missing:1"
+
+	err := restoreSessions([]tSession{broken, newTestSession("ok", []string{"/tmp"})}, restoreOptions{})
+	if err == nil {
+		t.Fatal("restoreSessions() error = nil, want error for broken session")
+	}
+
+	if s, _ := f.findSession("broken"); s != nil {
+		t.Error("partially restored session was not removed")
+	}
+
+	assertSessions(t, "ok[w1:/tmp]")
+}
+
+func TestNewSessionFromTemplate(t *testing.T) {
+	f := useFakeTmux(t)
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var hooks []string
+	prevHook := runHook
+	runHook = func(command, dir, sessionName string) error {
+		hooks = append(hooks, fmt.Sprintf("%s in %s for %s", command, dir, sessionName))
+		return nil
+	}
+	t.Cleanup(func() { runHook = prevHook })
+
+	tmpl := tTemplate{
+		Pre:  []string{"make deps"},
+		Post: []string{"echo {{.Name}}"},
+		Windows: []tWindow{
+			{Order: 1, Name: "code", Layout: "tiled", Panes: []tPane{
+				{Order: 1, Name: "editor", Path: ".", Commands: []string{"vim"}},
+				{Order: 2, Name: "server", Path: "api", Commands: []string{"make run PORT={{.port}}"}},
+			}},
+			{Order: 2, Name: "shell", Panes: []tPane{{Order: 1, Path: "missing"}}},
+		},
+	}
+
+	if err := newSessionFromTemplate(tmpl, "app", root, map[string]string{"port": "8080"}); err != nil {
+		t.Fatalf("newSessionFromTemplate() error = %v", err)
+	}
+
+	// Missing dirs fall back to the project root.
+	assertSessions(t, fmt.Sprintf("app[code:%s,%s shell:%s]", root, filepath.Join(root, "api"), root))
+
+	s, _ := f.findSession("app")
+	code := s.windows[0]
+	if code.panes[0].title != "editor" || code.panes[1].title != "server" {
+		t.Errorf("pane titles = %q, %q, want editor, server", code.panes[0].title, code.panes[1].title)
+	}
+
+	if got := code.panes[1].keys; !reflect.DeepEqual(got, []string{"make run PORT=8080 Enter"}) {
+		t.Errorf("keys sent to server pane = %q", got)
+	}
+
+	wantHooks := []string{
+		fmt.Sprintf("make deps in %s for app", root),
+		fmt.Sprintf("echo app in %s for app", root),
+	}
+	if !reflect.DeepEqual(hooks, wantHooks) {
+		t.Errorf("hooks = %q, want %q", hooks, wantHooks)
+	}
+}
+
+func TestNewSessionFromHandWrittenTemplate(t *testing.T) {
+	useFakeTmux(t)
+
+	root := t.TempDir()
+	filePath := filepath.Join(t.TempDir(), "dev.yaml")
+	content := "windows:\n  - name: code\n    panes:\n      - path: .\n      - path: .\n  - name: logs\n"
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := readTemplateFile(filePath, formatYAML)
+	if err != nil {
+		t.Fatalf("readTemplateFile() error = %v", err)
+	}
+
+	if err := newSessionFromTemplate(tmpl, "dev", root, nil); err != nil {
+		t.Fatalf("newSessionFromTemplate() error = %v", err)
+	}
+
+	// Windows follow the order of the file and logs gets a single pane.
+	assertSessions(t, fmt.Sprintf("dev[code:%s,%s logs:%s]", root, root, root))
+}
+
+func TestReadTemplateFileWithoutWindows(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "empty.yaml")
+	if err := os.WriteFile(filePath, []byte("root: /tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readTemplateFile(filePath, formatYAML); err == nil {
+		t.Fatal("readTemplateFile() error = nil, want error for template without windows")
+	}
+}
+
+func TestNewSessionFromTemplateExistingSession(t *testing.T) {
+	useFakeTmux(t, newTestSession("app", []string{"/tmp"}))
+
+	tmpl := tTemplate{Windows: []tWindow{{Order: 1, Name: "code", Panes: []tPane{{Order: 1, Path: "."}}}}}
+	if err := newSessionFromTemplate(tmpl, "app", t.TempDir(), nil); err == nil {
+		t.Fatal("newSessionFromTemplate() error = nil, want error for existing session")
+	}
+}
+
+func TestAssembleSessions(t *testing.T) {
+	pane := func(created, session, window, windowName, pane, path string) tmuxRecord {
+		return tmuxRecord{
+			"session_created": created, "session_name": session,
+			"window_index": window, "window_name": windowName, "window_layout": "tiled",
+			"pane_index": pane, "pane_title": "", "pane_current_path": path,
+			"pane_current_command": "zsh", "pane_pid": "1",
+		}
+	}
+
+	// tmux lists sessions by name, not by the time they were created.
+	panes := []tmuxRecord{
+		pane("300", "api", "1", "code", "1", "/api"),
+		pane("300", "api", "1", "code", "2", "/api/test"),
+		pane("300", "api", "3", "logs", "1", "/var/log"),
+		pane("100", "notes", "1", "todo", "1", "/notes"),
+		pane("200", "web", "2", "code", "1", "/web"),
+		pane("200", "web", "2", "code", "2", "/web/src"),
+		pane("200", "web", "2", "code", "3", "/web/test"),
+	}
+
+	sessions, err := assembleSessions(panes, nil)
+	if err != nil {
+		t.Fatalf("assembleSessions() error = %v", err)
+	}
+
+	want := []string{
+		"notes[todo:/notes]",
+		"web[code:/web,/web/src,/web/test]",
+		"api[code:/api,/api/test logs:/var/log]",
+	}
+	if got := describeSessions(sessions); !reflect.DeepEqual(got, want) {
+		t.Errorf("sessions = %q, want %q", got, want)
+	}
+
+	for i, s := range sessions {
+		if int(s.Order) != i+1 {
+			t.Errorf("session %s order = %d, want %d", s.Name, s.Order, i+1)
+		}
+	}
+
+	logs := sessions[2].Windows[1]
+	if logs.Order != 3 || logs.SessionWindow != "api:3" || logs.Panes[0].target() != "api:3.1" {
+		t.Errorf("logs window order = %d, target = %s, pane target = %s", logs.Order, logs.SessionWindow, logs.Panes[0].target())
+	}
+}
+
+func TestAssembleSessionsInvalidIndex(t *testing.T) {
+	panes := []tmuxRecord{{"session_created": "1", "session_name": "s", "window_index": "x", "pane_index": "1"}}
+
+	if _, err := assembleSessions(panes, nil); err == nil {
+		t.Fatal("assembleSessions() error = nil, want error for invalid window index")
+	}
+}
+
+// capturePerWindow captures sessions the way save did before list-panes -a:
+// list-windows for every session and list-panes for every window.
+func capturePerWindow() ([]tSession, error) {
+	windowFormat := tmuxFormat{"window_index", "window_name", "window_layout"}
+
+	ss, err := ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var tSessions []tSession
+	for i, s := range ss {
+		ts, _ := newTSession(s, i+1)
+
+		output, err := runner.Output("list-windows", "-t", ts.Name, "-F", windowFormat.String())
+		if err != nil {
+			return nil, err
+		}
+
+		windows, err := windowFormat.parse(output)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, w := range windows {
+			tw, err := newTWindow(w, ts.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			output, err := runner.Output("list-panes", "-t", tw.SessionWindow, "-F", paneFormat.String())
+			if err != nil {
+				return nil, err
+			}
+
+			panes, err := paneFormat.parse(output)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, p := range panes {
+				tp, err := newTPane(p, ts.Name, tw.SessionWindow)
+				if err != nil {
+					return nil, err
+				}
+
+				tw.Panes = append(tw.Panes, tp)
+			}
+
+			ts.Windows = append(ts.Windows, tw)
+		}
+
+		tSessions = append(tSessions, ts)
+	}
+
+	return tSessions, nil
+}
+
+// countingRunner counts tmux invocations made through it.
+type countingRunner struct {
+	TmuxRunner
+	calls int
+}
+
+func (c *countingRunner) Run(args ...string) error {
+	c.calls++
+	return c.TmuxRunner.Run(args...)
+}
+
+func (c *countingRunner) Output(args ...string) ([]byte, error) {
+	c.calls++
+	return c.TmuxRunner.Output(args...)
+}
+
+// BenchmarkCaptureSessions compares capture of 20 sessions with 4 windows
+// each on a private tmux server, where every tmux call starts a process.
+func BenchmarkCaptureSessions(b *testing.B) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		b.Skip("tmux is not installed")
+	}
+
+	server := tmuxServer{SocketName: fmt.Sprintf("tmxu-bench-%d", os.Getpid())}
+	tmux := execRunner{server: server}
+
+	// Start the server without user config and with a light shell in panes.
+	config := filepath.Join(b.TempDir(), "tmux.conf")
+	if err := os.WriteFile(config, []byte("set -g default-shell /bin/sh\n"), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	if err := exec.Command("tmux", "-L", server.SocketName, "-f", config, "new-session", "-d", "-s", "s0").Run(); err != nil {
+		b.Fatalf("Unable to start tmux server: %v", err)
+	}
+	b.Cleanup(func() { tmux.Run("kill-server") })
+
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("s%d", i)
+
+		commands := [][]string{
+			{"split-window", "-t", name + ":", "-c", "/tmp"},
+			{"new-window", "-t", name + ":", "-c", "/usr"},
+			{"new-window", "-t", name + ":", "-c", "/etc"},
+			{"split-window", "-t", name + ":", "-c", "/var"},
+			{"new-window", "-t", name + ":", "-c", "/"},
+		}
+		if i > 0 {
+			commands = append([][]string{{"new-session", "-d", "-s", name}}, commands...)
+		}
+
+		for _, c := range commands {
+			if err := tmux.Run(c...); err != nil {
+				b.Fatalf("%v error = %v", c, err)
+			}
+		}
+	}
+
+	prevRunner, prevProcs := runner, listProcesses
+	listProcesses = func() (processTable, error) { return processTable{}, nil }
+	b.Cleanup(func() { runner, listProcesses = prevRunner, prevProcs })
+
+	benchmarks := []struct {
+		name    string
+		capture func() ([]tSession, error)
+	}{
+		{"per-window", capturePerWindow},
+		{"list-panes-a", captureSessions},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			counter := &countingRunner{TmuxRunner: tmux}
+			runner = counter
+
+			for b.Loop() {
+				sessions, err := bm.capture()
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if len(sessions) != 20 || len(sessions[19].Windows) != 4 {
+					b.Fatalf("captured %d sessions", len(sessions))
+				}
+			}
+
+			b.ReportMetric(float64(counter.calls)/float64(b.N), "tmux-calls/op")
+		})
+	}
+}
+
+func TestRestoreSessionsKeepsPaneOrder(t *testing.T) {
+	useFakeTmux(t, newTestSession("work", []string{"/tmp", "/usr"}))
+
+	saved := newTestSession("work", []string{"/tmp", "/usr", "/etc", "/var"}, []string{"/a", "/b", "/c"})
+	for i := range saved.Windows[0].Panes {
+		saved.Windows[0].Panes[i].Name = fmt.Sprintf("p%d", i+1)
+	}
+
+	if err := restoreSessions([]tSession{saved}, restoreOptions{onConflict: conflictMerge}); err != nil {
+		t.Fatalf("restoreSessions() error = %v", err)
+	}
+
+	if err := restoreSessions([]tSession{saved.withName("copy")}, restoreOptions{}); err != nil {
+		t.Fatalf("restoreSessions() error = %v", err)
+	}
+
+	assertSessions(t,
+		"work[w1:/tmp,/usr,/etc,/var w2:/a,/b,/c]",
+		"copy[w1:/tmp,/usr,/etc,/var w2:/a,/b,/c]",
+	)
+
+	sessions, _ := captureSessions()
+	for i, p := range sessions[1].Windows[0].Panes {
+		if want := fmt.Sprintf("p%d", i+1); p.Name != want {
+			t.Errorf("pane %d title = %q, want %q", i+1, p.Name, want)
+		}
+	}
+}
+
+func TestNewSessionFromTemplateSendsCommandsToEachPane(t *testing.T) {
+	f := useFakeTmux(t)
+
+	tmpl := tTemplate{Windows: []tWindow{
+		{Order: 1, Name: "code", Commands: []string{"clear"}, Panes: []tPane{
+			{Order: 1, Name: "p1", Path: ".", Commands: []string{"one"}},
+			{Order: 2, Name: "p2", Path: ".", Commands: []string{"two"}},
+			{Order: 3, Name: "p3", Path: ".", Commands: []string{"three"}},
+			{Order: 4, Name: "p4", Path: ".", Commands: []string{"four"}},
+		}},
+	}}
+
+	if err := newSessionFromTemplate(tmpl, "app", t.TempDir(), nil); err != nil {
+		t.Fatalf("newSessionFromTemplate() error = %v", err)
+	}
+
+	s, _ := f.findSession("app")
+	for i, p := range s.windows[0].panes {
+		want := tmpl.Windows[0].Panes[i]
+		if p.title != want.Name {
+			t.Errorf("pane %d title = %q, want %q", i+1, p.title, want.Name)
+		}
+
+		if keys := []string{"clear Enter", want.Commands[0] + " Enter"}; !reflect.DeepEqual(p.keys, keys) {
+			t.Errorf("pane %d keys = %q, want %q", i+1, p.keys, keys)
+		}
+	}
+}
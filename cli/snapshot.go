@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const snapshotsDir = "snapshots"
+const autoSnapshotPrefix = "auto-"
+const snapshotTimeLayout = "2006-01-02T15-04-05"
+const defaultSnapshotsKeep = 10
+
+type snapshotInfo struct {
+	Name     string
+	Saved    time.Time
+	Sessions int
+	Server   tmuxServer
+}
+
+// autoSnapshotName returns name of timestamped snapshot, e.g.
+// auto-2026-01-02T15-04-05. Only those snapshots are pruned.
+func autoSnapshotName(t time.Time) string {
+	return autoSnapshotPrefix + t.Format(snapshotTimeLayout)
+}
+
+func validateSnapshotName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("Invalid snapshot name: %s \n", name)
+	}
+
+	return nil
+}
+
+func getSnapshotsDirPath() (string, error) {
+	path, err := getStateSubdirPath(snapshotsDir)
+	if err != nil {
+		return "", fmt.Errorf("Unable to get snapshots dir \n")
+	}
+
+	return path, nil
+}
+
+func getSnapshotFilePath(name string) (string, error) {
+	path, err := getSnapshotsDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(path, name+".json"), nil
+}
+
+func saveSnapshotFile(name string, data []tSession) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	path, err := getSnapshotsDirPath()
+	if err != nil {
+		return fmt.Errorf("Unable to get snapshots dir \n")
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("Unable to create tmxu snapshots dir: %s \n", path)
+	}
+
+	j, err := json.MarshalIndent(sessionsFile{SchemaVersion: schemaVersion, tmuxServer: activeServer, Sessions: data}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Cannot marshal session data \n")
+	}
+
+	filePath, _ := getSnapshotFilePath(name)
+	err = withConfigLock(func() error {
+		return writeFileAtomic(filePath, j, 0644)
+	})
+	if err != nil {
+		return fmt.Errorf("Cannot save snapshot file at path: %s \n", filePath)
+	}
+
+	return nil
+}
+
+func loadSnapshotFile(name string) ([]tSession, error) {
+	data, err := readSnapshotFile(name)
+	return data.Sessions, err
+}
+
+// readSnapshotFile returns snapshot together with the server its sessions
+// were saved from.
+func readSnapshotFile(name string) (sessionsFile, error) {
+	var data sessionsFile
+
+	if err := validateSnapshotName(name); err != nil {
+		return data, err
+	}
+
+	filePath, err := getSnapshotFilePath(name)
+	if err != nil {
+		return data, fmt.Errorf("Unable to get file path \n")
+	}
+
+	err = readVersionedFile(filePath, formatJSON, sessionsMigrations, &data)
+	return data, err
+}
+
+// saveSessionsSnapshot writes sessions to the sessions file and to snapshot
+// with given name, optionally together with content of every pane.
+func saveSessionsSnapshot(sessions []tSession, name string, scrollback bool) error {
+	return withConfigLock(func() error {
+		if err := writeSnapshot(sessions, name, scrollback); err != nil {
+			return err
+		}
+
+		if err := saveSessionsFile(sessions); err != nil {
+			path, _ := getSessionFilePath()
+			return fmt.Errorf("Unable to save tmux sessions to file: %s \n", path)
+		}
+
+		return nil
+	})
+}
+
+// saveSnapshot writes sessions only to snapshot with given name. The sessions
+// file is left untouched, so a save of some sessions does not drop the rest
+// from the last save.
+func saveSnapshot(sessions []tSession, name string, scrollback bool) error {
+	return withConfigLock(func() error {
+		return writeSnapshot(sessions, name, scrollback)
+	})
+}
+
+func writeSnapshot(sessions []tSession, name string, scrollback bool) error {
+	if scrollback {
+		if err := saveScrollbacks(sessions, name); err != nil {
+			return err
+		}
+	}
+
+	return saveSnapshotFile(name, sessions)
+}
+
+// listSnapshotFiles returns all saved snapshots, newest first.
+func listSnapshotFiles() ([]snapshotInfo, error) {
+	path, err := getSnapshotsDirPath()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read snapshots dir \n")
+	}
+
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Cannot read snapshots dir \n")
+	}
+
+	var snapshots []snapshotInfo
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read snapshot file: %s \n", e.Name())
+		}
+
+		data, err := readSnapshotFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshotInfo{
+			Name:     name,
+			Saved:    info.ModTime(),
+			Sessions: len(data.Sessions),
+			Server:   data.tmuxServer,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Saved.After(snapshots[j].Saved)
+	})
+
+	return snapshots, nil
+}
+
+// pruneSnapshots removes the oldest automatic snapshots so that at most keep
+// of them are left. Named snapshots are never removed. Returns names of
+// removed snapshots.
+func pruneSnapshots(keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	snapshots, err := listSnapshotFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var auto []string
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Name, autoSnapshotPrefix) {
+			auto = append(auto, s.Name)
+		}
+	}
+
+	// Timestamped names sort the same way as their dates, newest first.
+	sort.Sort(sort.Reverse(sort.StringSlice(auto)))
+
+	var removed []string
+	err = withConfigLock(func() error {
+		for _, name := range auto[Min(keep, len(auto)):] {
+			if err := deleteSnapshotFile(name); err != nil {
+				return err
+			}
+
+			removed = append(removed, name)
+		}
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// deleteSnapshotFile removes snapshot together with its saved scrollback.
+func deleteSnapshotFile(name string) error {
+	filePath, err := getSnapshotFilePath(name)
+	if err != nil {
+		return fmt.Errorf("Unable to get file path \n")
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("Unable to delete snapshot: %s \n", filePath)
+	}
+
+	path, err := getScrollbackDirPath()
+	if err != nil {
+		return fmt.Errorf("Unable to get scrollback dir \n")
+	}
+
+	if err := os.RemoveAll(filepath.Join(path, name)); err != nil {
+		return fmt.Errorf("Unable to delete scrollback for snapshot: %s \n", name)
+	}
+
+	return nil
+}
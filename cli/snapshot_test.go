@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// runCmd runs cmd with args as if passed on the command line, without asking
+// for confirmation.
+func runCmd(t *testing.T, cmd Cmd, args ...string) {
+	t.Helper()
+
+	prevArgs, prevSkip := os.Args, cfg.SkipConfirm
+	os.Args = append([]string{"tmxu", cmd.Command}, args...)
+	cfg.SkipConfirm = true
+	t.Cleanup(func() { os.Args, cfg.SkipConfirm = prevArgs, prevSkip })
+
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%s %q error = %v", cmd.Command, args, err)
+	}
+}
+
+func TestSaveFilteredSessionsKeepsLastSave(t *testing.T) {
+	useFakeTmux(t,
+		newTestSession("work-api", []string{"/api"}),
+		newTestSession("notes", []string{"/notes"}),
+	)
+
+	runCmd(t, saveSessionsCmd, "-name", "full")
+	runCmd(t, saveSessionsCmd, "-name", "work", "-include", "work-*")
+
+	saved, err := loadSessionsFile()
+	if err != nil {
+		t.Fatalf("loadSessionsFile() error = %v", err)
+	}
+
+	want := []string{"work-api[w1:/api]", "notes[w1:/notes]"}
+	if got := describeSessions(saved); !reflect.DeepEqual(got, want) {
+		t.Errorf("last save = %q, want %q", got, want)
+	}
+
+	snapshot, err := loadSnapshotFile("work")
+	if err != nil {
+		t.Fatalf("loadSnapshotFile() error = %v", err)
+	}
+
+	if got := describeSessions(snapshot); !reflect.DeepEqual(got, []string{"work-api[w1:/api]"}) {
+		t.Errorf("snapshot = %q, want only work-api", got)
+	}
+}
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// legacyTemplateValue is the placeholder written by older versions of
+// save-template in place of paths and session names.
+const legacyTemplateValue = "TEMP_VALUE"
+
+const rootPlaceholder = "{{.Root}}"
+const namePlaceholder = "{{.Name}}"
+
+// templateVars are values available to placeholders in templates:
+// {{.Root}} is the -path of new session, {{.Name}} its name, {{.Env.X}} the
+// environment variable X and {{.key}} a variable passed with -var key=value.
+type templateVars map[string]any
+
+func newTemplateVars(root, name string, vars map[string]string) (templateVars, error) {
+	env := make(map[string]string)
+	for _, e := range os.Environ() {
+		k, v, _ := strings.Cut(e, "=")
+		env[k] = v
+	}
+
+	data := templateVars{
+		"Root": root,
+		"Name": name,
+		"Env":  env,
+	}
+
+	for k, v := range vars {
+		if _, ok := data[k]; ok {
+			return nil, fmt.Errorf("Variable %s is reserved, use one of the other names \n", k)
+		}
+
+		data[k] = v
+	}
+
+	return data, nil
+}
+
+// expand executes value as text/template against vars. Unknown variables are
+// reported as errors instead of being silently replaced with empty string.
+func (vars templateVars) expand(value string) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	t, err := template.New("value").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("Invalid placeholder in: %s \n", value)
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, map[string]any(vars)); err != nil {
+		return "", fmt.Errorf("Unable to expand placeholder in: %s (%s) \n", value, err.Error())
+	}
+
+	return b.String(), nil
+}
+
+// expandAll expands every value, returning new slice.
+func (vars templateVars) expandAll(values []string) ([]string, error) {
+	var expanded []string
+
+	for _, v := range values {
+		e, err := vars.expand(v)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, e)
+	}
+
+	return expanded, nil
+}
+
+// expandTemplate returns copy of t with placeholders expanded in window names,
+// pane titles, paths, commands and hooks. Command lines captured from running
+// programs are used as they are. Pane paths from templates saved by older
+// versions point to {{.Root}}.
+func expandTemplate(t tTemplate, vars templateVars) (tTemplate, error) {
+	var err error
+
+	if t.Pre, err = vars.expandAll(t.Pre); err != nil {
+		return t, err
+	}
+
+	if t.Post, err = vars.expandAll(t.Post); err != nil {
+		return t, err
+	}
+
+	t.Windows = append([]tWindow(nil), t.Windows...)
+	for i := range t.Windows {
+		window := &t.Windows[i]
+		if window.Name, err = vars.expand(window.Name); err != nil {
+			return t, err
+		}
+
+		if window.Commands, err = vars.expandAll(window.Commands); err != nil {
+			return t, err
+		}
+
+		window.Panes = append([]tPane(nil), window.Panes...)
+		for j := range window.Panes {
+			pane := &window.Panes[j]
+
+			if pane.Path == "" || pane.Path == legacyTemplateValue {
+				pane.Path = rootPlaceholder
+			}
+
+			for _, field := range []*string{&pane.Name, &pane.Path} {
+				if *field, err = vars.expand(*field); err != nil {
+					return t, err
+				}
+			}
+
+			if pane.Commands, err = vars.expandAll(pane.Commands); err != nil {
+				return t, err
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// escapePlaceholders returns value which expands back to itself, so text
+// like docker ps --format '{{.Names}}' is not taken for a placeholder.
+func escapePlaceholders(value string) string {
+	return strings.ReplaceAll(value, "{{", `{{"{{"}}`)
+}
+
+// literalTemplate returns copy of t with placeholders escaped in every value
+// expandTemplate expands. It is used for templates made from captured
+// sessions or from files of other tools, whose values are meant literally.
+func literalTemplate(t tTemplate) tTemplate {
+	escapeAll := func(values []string) []string {
+		var escaped []string
+		for _, v := range values {
+			escaped = append(escaped, escapePlaceholders(v))
+		}
+
+		return escaped
+	}
+
+	t.Pre = escapeAll(t.Pre)
+	t.Post = escapeAll(t.Post)
+
+	t.Windows = append([]tWindow(nil), t.Windows...)
+	for i := range t.Windows {
+		window := &t.Windows[i]
+		window.Name = escapePlaceholders(window.Name)
+		window.Commands = escapeAll(window.Commands)
+
+		window.Panes = append([]tPane(nil), window.Panes...)
+		for j := range window.Panes {
+			pane := &window.Panes[j]
+			pane.Name = escapePlaceholders(pane.Name)
+			pane.Path = escapePlaceholders(pane.Path)
+			pane.Commands = escapeAll(pane.Commands)
+		}
+	}
+
+	return t
+}
+
+// relativePanePath returns path relative to root, e.g. web for
+// /src/app/web with root /src/app. Paths outside of root stay absolute.
+func relativePanePath(path, root string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+
+	return rel
+}
+
+// resolvePanePath resolves relative pane path against root. Falls back to
+// root when the directory does not exist.
+func resolvePanePath(path, root string) string {
+	path = expandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return root
+	}
+
+	return path
+}
+
+// expandHome replaces leading ~ in path with home dir of the user.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(homeDir, path[1:])
+}
+
+// varsFlag collects repeated -var key=value flags.
+type varsFlag map[string]string
+
+func (v varsFlag) String() string {
+	var pairs []string
+	for k, val := range v {
+		pairs = append(pairs, k+"="+val)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (v varsFlag) Set(value string) error {
+	k, val, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value, got: %s", value)
+	}
+
+	v[k] = val
+	return nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandTemplateKeepsCommandLine(t *testing.T) {
+	vars, err := newTemplateVars("/srv/app", "app", map[string]string{"port": "8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := tTemplate{Windows: []tWindow{{Name: "{{.Name}}", Panes: []tPane{{
+		Name:        "server",
+		Path:        "{{.Root}}/api",
+		Commands:    []string{"make run PORT={{.port}}"},
+		CommandLine: "docker ps --format '{{.Names}}'",
+	}}}}}
+
+	got, err := expandTemplate(tmpl, vars)
+	if err != nil {
+		t.Fatalf("expandTemplate() error = %v", err)
+	}
+
+	window := got.Windows[0]
+	pane := window.Panes[0]
+	if window.Name != "app" || pane.Path != "/srv/app/api" || pane.Commands[0] != "make run PORT=8080" {
+		t.Errorf("expanded window = %q, path = %q, commands = %q", window.Name, pane.Path, pane.Commands)
+	}
+
+	if pane.CommandLine != "docker ps --format '{{.Names}}'" {
+		t.Errorf("command line = %q, want it unchanged", pane.CommandLine)
+	}
+}
+
+func TestLiteralTemplate(t *testing.T) {
+	vars, err := newTemplateVars("/srv/app", "app", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := tTemplate{
+		Pre: []string{"echo '{{ready}}'"},
+		Windows: []tWindow{{Name: "{{logs}}", Commands: []string{"clear"}, Panes: []tPane{{
+			Name:     "{{.Names}}",
+			Path:     "api",
+			Commands: []string{"docker ps --format '{{.Names}}'", "echo }}"},
+		}}}},
+	}
+
+	got, err := expandTemplate(literalTemplate(tmpl), vars)
+	if err != nil {
+		t.Fatalf("expandTemplate() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, tmpl) {
+		t.Errorf("expandTemplate(literalTemplate()) = %+v, want %+v", got, tmpl)
+	}
+
+	if tmpl.Windows[0].Panes[0].Name != "{{.Names}}" {
+		t.Error("literalTemplate() changed the original template")
+	}
+}
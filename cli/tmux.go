@@ -3,10 +3,7 @@ package cli
 import (
 	"errors"
 	"fmt"
-	"os"
-	"os/exec"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -15,6 +12,12 @@ type tSession struct {
 	Order   int16     `json:"order"`
 	Name    string    `json:"name"`
 	Windows []tWindow `json:"windows"`
+	// Pre and Post are shell commands run by templates before and after
+	// the session is created.
+	Pre  []string `json:"pre,omitempty"`
+	Post []string `json:"post,omitempty"`
+	// Root is project root of template used when new-session -path is not set.
+	Root string `json:"root,omitempty"`
 }
 
 type tSessionSimple struct {
@@ -23,17 +26,16 @@ type tSessionSimple struct {
 	Widnows int
 }
 
-func newTSessionSimple(s string) tSessionSimple {
-	parts := strings.Split(s, " ")
+func newTSessionSimple(r tmuxRecord) tSessionSimple {
 	// Parse Unix timestamp from tmux (seconds since epoch)
-	createdTimestamp, _ := strconv.ParseInt(parts[0], 10, 64)
-	createdTime := time.Unix(createdTimestamp, 0)
+	createdTimestamp, _ := r.int("session_created")
+	createdTime := time.Unix(int64(createdTimestamp), 0)
 	duration := time.Since(createdTime)
-	windows, _ := strconv.Atoi(parts[2])
+	windows, _ := r.int("session_windows")
 
 	return tSessionSimple{
 		Created: duration,
-		Name:    parts[1],
+		Name:    r["session_name"],
 		Widnows: windows,
 	}
 }
@@ -50,12 +52,10 @@ func (s tSessionSimple) Desc() string {
 
 type tTemplate = tSession
 
-func newTSession(tmuxSession string, order int) (tSession, error) {
-	parts := strings.Split(tmuxSession, " ")
-
+func newTSession(tmuxSession tmuxRecord, order int) (tSession, error) {
 	return tSession{
 		Order: int16(order),
-		Name:  parts[1],
+		Name:  tmuxSession["session_name"],
 	}, nil
 }
 
@@ -66,22 +66,23 @@ type tWindow struct {
 	SessionName   string  `json:"sessionName"`
 	SessionWindow string  `json:"sessionWindow"`
 	Panes         []tPane `json:"panes"`
+	// Commands are sent to every pane of the window when created from
+	// template, before commands of the pane itself.
+	Commands []string `json:"commands,omitempty"`
 }
 
-func newTWindow(tmuxWindow, sessionName string) (tWindow, error) {
-	parts := strings.Split(tmuxWindow, " ")
-
-	order, err := strconv.Atoi(parts[0])
+func newTWindow(tmuxWindow tmuxRecord, sessionName string) (tWindow, error) {
+	order, err := tmuxWindow.int("window_index")
 	if err != nil {
-		return tWindow{}, fmt.Errorf("unable to parse order for window: %s", parts[1])
+		return tWindow{}, fmt.Errorf("unable to parse order for window: %s", tmuxWindow["window_name"])
 	}
 
 	return tWindow{
 		Order:         int16(order),
-		Name:          parts[1],
-		Layout:        parts[2],
+		Name:          tmuxWindow["window_name"],
+		Layout:        tmuxWindow["window_layout"],
 		SessionName:   sessionName,
-		SessionWindow: fmt.Sprintf("%s:%s", sessionName, parts[0]),
+		SessionWindow: fmt.Sprintf("%s:%d", sessionName, order),
 	}, nil
 }
 
@@ -91,42 +92,119 @@ type tPane struct {
 	Path          string `json:"path"`
 	SessionName   string `json:"sessionName"`
 	SessionWindow string `json:"sessionWindow"`
+	Command       string `json:"command,omitempty"`
+	CommandLine   string `json:"commandLine,omitempty"`
+	Scrollback    string `json:"scrollback,omitempty"`
+	// Commands are sent to the pane when created from template.
+	Commands []string `json:"commands,omitempty"`
+
+	pid string
+	// id is set for panes created by id, like %3, and wins over Order.
+	id string
 }
 
-func newTPane(tmuxPane, sessionName, sessionWindow string) (tPane, error) {
-	parts := strings.Split(tmuxPane, " ")
-
-	order, err := strconv.Atoi(parts[0])
+func newTPane(tmuxPane tmuxRecord, sessionName, sessionWindow string) (tPane, error) {
+	order, err := tmuxPane.int("pane_index")
 	if err != nil {
-		return tPane{}, fmt.Errorf("unable to parse order for pane: %s", parts[1])
+		return tPane{}, fmt.Errorf("unable to parse order for pane: %s", tmuxPane["pane_title"])
 	}
 
-	return tPane{
+	tp := tPane{
 		Order:         int16(order),
-		Name:          parts[1],
-		Path:          parts[2],
+		Name:          tmuxPane["pane_title"],
+		Path:          tmuxPane["pane_current_path"],
 		SessionWindow: sessionWindow,
 		SessionName:   sessionName,
-	}, nil
+	}
+
+	if command := tmuxPane["pane_current_command"]; command != "" && !isShell(command) {
+		tp.Command = command
+		tp.pid = tmuxPane["pane_pid"]
+	}
+
+	return tp, nil
+}
+
+func (p tPane) target() string {
+	if p.id != "" {
+		return p.id
+	}
+
+	return fmt.Sprintf("%s.%d", p.SessionWindow, p.Order)
+}
+
+// withName returns copy of s with windows and panes targeting session name.
+func (s tSession) withName(name string) tSession {
+	windows := make([]tWindow, len(s.Windows))
+	for i, w := range s.Windows {
+		windows[i] = w.withOrder(name, w.Order)
+	}
+
+	s.Name = name
+	s.Windows = windows
+
+	return s
+}
+
+// withOrder returns copy of w with its panes targeting window order of
+// session name.
+func (w tWindow) withOrder(name string, order int16) tWindow {
+	w.Order = order
+	w.SessionName = name
+	w.SessionWindow = fmt.Sprintf("%s:%d", name, order)
+
+	panes := make([]tPane, len(w.Panes))
+	for i, p := range w.Panes {
+		p.SessionName = name
+		p.SessionWindow = w.SessionWindow
+		panes[i] = p
+	}
+
+	w.Panes = panes
+
+	return w
+}
+
+// unusedSessionName returns name, or name with the lowest free numeric suffix
+// starting at 2 when a session of that name exists.
+func unusedSessionName(name string) string {
+	candidate := name
+	for i := 2; ; i++ {
+		if hs, _ := HasSession(candidate); !hs {
+			return candidate
+		}
+
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
 }
 
 var errorSessionExists = errors.New("session exists")
 
-func ListSessions() ([]string, error) {
-	output, err := exec.Command("tmux", "list-sessions", "-F", "#{session_created} #{session_name} #{session_windows}").Output()
+// exactTarget makes tmux match the session name of target exactly. Otherwise
+// tmux falls back to a session whose name starts with it, so "kee" would
+// target a running "keep". Ids of panes and windows are kept as they are.
+func exactTarget(target string) string {
+	if strings.HasPrefix(target, "=") || strings.HasPrefix(target, "%") || strings.HasPrefix(target, "@") {
+		return target
+	}
+
+	return "=" + target
+}
+
+func ListSessions() ([]tmuxRecord, error) {
+	output, err := runner.Output("list-sessions", "-F", sessionFormat.String())
 	if err != nil {
 		return nil, fmt.Errorf("unable to list tmux sessions")
 	}
 
-	ss := strings.Split(
-		strings.TrimSpace(string(output)), "\n",
-	)
+	ss, err := sessionFormat.parse(output)
+	if err != nil {
+		return nil, err
+	}
 
-	sort.Slice(ss, func(i, j int) bool {
-		partsI := strings.Split(ss[i], " ")
-		partsJ := strings.Split(ss[j], " ")
-		tsI, _ := strconv.ParseInt(partsI[0], 10, 8)
-		tsJ, _ := strconv.ParseInt(partsJ[0], 10, 8)
+	sort.SliceStable(ss, func(i, j int) bool {
+		tsI, _ := ss[i].int("session_created")
+		tsJ, _ := ss[j].int("session_created")
 		return tsI < tsJ
 	})
 
@@ -139,7 +217,7 @@ func NewSession(session tSession, force bool) error {
 	if hs == true && !force {
 		return errorSessionExists
 	} else if hs == true && force {
-		err := exec.Command("tmux", "kill-session", "-t", session.Name).Run()
+		err := runner.Run("kill-session", "-t", exactTarget(session.Name))
 		if err != nil {
 			return fmt.Errorf("Unable to kill session: %s \n", session.Name)
 		}
@@ -150,7 +228,7 @@ func NewSession(session tSession, force bool) error {
 		startingDir = session.Windows[0].Panes[0].Path
 	}
 
-	err := exec.Command("tmux", "new-session", "-c", startingDir, "-d", "-s", session.Name).Run()
+	err := runner.Run("new-session", "-c", startingDir, "-d", "-s", session.Name)
 	if err != nil {
 		return fmt.Errorf("unable to create session: %s %s \n", session.Name, err.Error())
 	}
@@ -158,32 +236,48 @@ func NewSession(session tSession, force bool) error {
 	return nil
 }
 
-func AttachToSession(sessionName string) error {
-	cmd := exec.Command("tmux", "attach", "-t", sessionName)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+func KillSession(sessionName string) error {
+	if err := runner.Run("kill-session", "-t", exactTarget(sessionName)); err != nil {
+		return fmt.Errorf("unable to kill session: %s", sessionName)
+	}
 
-	if err := cmd.Run(); err != nil {
-		return err
+	return nil
+}
+
+func KillWindow(window tWindow) error {
+	if err := runner.Run("kill-window", "-t", exactTarget(window.SessionWindow)); err != nil {
+		return fmt.Errorf("unable to kill window: %s", window.SessionWindow)
 	}
 
 	return nil
 }
 
-func ListWindows(sessionName string) ([]string, error) {
-	output, err := exec.Command("tmux", "list-windows", "-t", sessionName, "-F", "#{window_index} #{window_name} #{window_layout}").Output()
-	if err != nil {
-		return nil, fmt.Errorf("unable to list tmux windows for session: %s", sessionName)
+func KillPane(pane tPane) error {
+	if err := runner.Run("kill-pane", "-t", exactTarget(pane.target())); err != nil {
+		return fmt.Errorf("unable to kill pane: %s", pane.target())
+	}
+
+	return nil
+}
+
+func RenameSession(sessionName, newName string) error {
+	if err := runner.Run("rename-session", "-t", exactTarget(sessionName), newName); err != nil {
+		return fmt.Errorf("unable to rename session: %s", sessionName)
+	}
+
+	return nil
+}
+
+func AttachToSession(sessionName string) error {
+	if err := runner.Interactive("attach", "-t", sessionName); err != nil {
+		return err
 	}
 
-	return strings.Split(
-		strings.TrimSpace(string(output)), "\n",
-	), nil
+	return nil
 }
 
 func HasSession(sessionName string) (bool, error) {
-	output, err := exec.Command("tmux", "has-session", "-t", sessionName).Output()
+	output, err := runner.Output("has-session", "-t", exactTarget(sessionName))
 
 	if err != nil {
 		return false, fmt.Errorf("unable to validate session: %s", sessionName)
@@ -207,7 +301,7 @@ func NewWindow(window tWindow) error {
 		return nil
 	} else {
 		firstPanePath := window.Panes[0].Path
-		err := exec.Command("tmux", "new-window", "-c", firstPanePath, "-t", window.SessionWindow, "-n", window.Name).Run()
+		err := runner.Run("new-window", "-c", firstPanePath, "-t", exactTarget(window.SessionWindow), "-n", window.Name)
 		if err != nil {
 			return fmt.Errorf("unable to create window: %s \n", window.Name)
 		}
@@ -217,7 +311,7 @@ func NewWindow(window tWindow) error {
 }
 
 func SetWindowLayout(window tWindow) error {
-	err := exec.Command("tmux", "select-layout", "-t", window.SessionWindow, window.Layout).Run()
+	err := runner.Run("select-layout", "-t", exactTarget(window.SessionWindow), window.Layout)
 	if err != nil {
 		return fmt.Errorf("unable to select layout for window: %s", window.SessionWindow)
 	}
@@ -226,7 +320,7 @@ func SetWindowLayout(window tWindow) error {
 }
 
 func RenameWindow(window tWindow) error {
-	err := exec.Command("tmux", "rename-window", "-t", window.SessionWindow, window.Name).Run()
+	err := runner.Run("rename-window", "-t", exactTarget(window.SessionWindow), window.Name)
 	if err != nil {
 		return fmt.Errorf("unable to rename window: %s", window.SessionWindow)
 	}
@@ -234,21 +328,32 @@ func RenameWindow(window tWindow) error {
 	return nil
 }
 
-func ListPanes(sessionWindow string) ([]string, error) {
-	output, err := exec.Command("tmux", "list-panes", "-t", sessionWindow, "-F", "#{pane_index} #{pane_title} #{pane_current_path}").Output()
+// ListAllPanes lists panes of every session of the server.
+func ListAllPanes() ([]tmuxRecord, error) {
+	output, err := runner.Output("list-panes", "-a", "-F", paneFormat.String())
 	if err != nil {
-		return nil, fmt.Errorf("unable to list panes for window: %s \n", sessionWindow)
+		return nil, fmt.Errorf("unable to list tmux panes")
 	}
 
-	return strings.Split(
-		strings.TrimSpace(string(output)), "\n",
-	), nil
+	return paneFormat.parse(output)
+}
+
+// ListSessionPanes lists panes of every window of the session.
+func ListSessionPanes(sessionName string) ([]tmuxRecord, error) {
+	output, err := runner.Output("list-panes", "-s", "-t", exactTarget(sessionName+":"), "-F", paneFormat.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list panes for session: %s \n", sessionName)
+	}
 
+	return paneFormat.parse(output)
 }
 
+// NewPane creates pane by splitting the pane before it. tmux puts new pane
+// right after the split one, so panes created in order keep their saved index.
 func NewPane(pane tPane) error {
 	if pane.Order != 1 {
-		err := exec.Command("tmux", "split-window", "-d", "-c", pane.Path, "-t", pane.SessionWindow).Run()
+		previous := fmt.Sprintf("%s.%d", pane.SessionWindow, pane.Order-1)
+		err := runner.Run("split-window", "-d", "-c", pane.Path, "-t", exactTarget(previous))
 		if err != nil {
 			return fmt.Errorf("unable to create pane: %s for window: %s \n", pane.Name, pane.SessionWindow)
 		}
@@ -262,12 +367,51 @@ func NewPane(pane tPane) error {
 	return nil
 }
 
+// SplitPane splits pane target and returns id of the new pane, which stays
+// valid when other panes of the window are split later.
+func SplitPane(target, path string) (string, error) {
+	output, err := runner.Output("split-window", "-d", "-P", "-F", "#{pane_id}", "-c", path, "-t", exactTarget(target))
+	if err != nil {
+		return "", fmt.Errorf("unable to split pane: %s \n", target)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// PaneID returns id of pane target, or of the active pane of window target.
+func PaneID(target string) (string, error) {
+	output, err := runner.Output("display-message", "-p", "-t", exactTarget(target), "#{pane_id}")
+	if err != nil {
+		return "", fmt.Errorf("unable to find pane: %s \n", target)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
 func RenamePane(pane tPane) error {
-	targetPane := fmt.Sprintf("%s.%d", pane.SessionWindow, pane.Order)
-	err := exec.Command("tmux", "select-pane", "-t", targetPane, "-T", pane.Name).Run()
+	targetPane := pane.target()
+	err := runner.Run("select-pane", "-t", exactTarget(targetPane), "-T", pane.Name)
 	if err != nil {
 		return fmt.Errorf("unable to rename pane: %s \n", targetPane)
 	}
 
 	return nil
 }
+
+func SendKeys(target, keys string) error {
+	err := runner.Run("send-keys", "-t", exactTarget(target), keys, "Enter")
+	if err != nil {
+		return fmt.Errorf("unable to send keys to pane: %s \n", target)
+	}
+
+	return nil
+}
+
+func CapturePane(target string) ([]byte, error) {
+	output, err := runner.Output("capture-pane", "-p", "-e", "-J", "-S", "-", "-t", exactTarget(target))
+	if err != nil {
+		return nil, fmt.Errorf("unable to capture pane: %s \n", target)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// formatDelimiter separates fields of tmux format output. Unlike spaces or
+// colons it does not show up in session and window names, titles or paths.
+const formatDelimiter = "\x1f"
+
+// tmuxFormat is a list of tmux format variables, like session_name, queried
+// together with -F.
+type tmuxFormat []string
+
+var sessionFormat = tmuxFormat{"session_created", "session_name", "session_windows"}
+
+// paneFormat describes pane together with its window and session, so state
+// of the whole server is captured with a single list-panes -a.
+var paneFormat = tmuxFormat{
+	"session_created", "session_name",
+	"window_index", "window_name", "window_layout",
+	"pane_index", "pane_title", "pane_current_path", "pane_current_command", "pane_pid",
+}
+
+// String returns format for -F with variables separated by formatDelimiter.
+func (f tmuxFormat) String() string {
+	vars := make([]string, len(f))
+	for i, v := range f {
+		vars[i] = "#{" + v + "}"
+	}
+
+	return strings.Join(vars, formatDelimiter)
+}
+
+// tmuxRecord holds values of format variables from one line of tmux output.
+type tmuxRecord map[string]string
+
+// parse splits output of tmux command run with f into records, one per line.
+// Line with a different number of fields than f is an error, so a value
+// containing the delimiter never shifts other fields.
+func (f tmuxFormat) parse(output []byte) ([]tmuxRecord, error) {
+	var records []tmuxRecord
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(output), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+
+		values := strings.Split(line, formatDelimiter)
+		if len(values) != len(f) {
+			return nil, fmt.Errorf("unexpected tmux output, expected %d fields: %q", len(f), line)
+		}
+
+		r := make(tmuxRecord, len(f))
+		for i, v := range f {
+			r[v] = values[i]
+		}
+
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// int returns value of numeric variable name.
+func (r tmuxRecord) int(name string) (int, error) {
+	v, err := strconv.Atoi(r[name])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", name, r[name])
+	}
+
+	return v, nil
+}
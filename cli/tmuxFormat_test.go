@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTmuxFormatString(t *testing.T) {
+	f := tmuxFormat{"session_name", "window_index"}
+
+	if got, want := f.String(), "#{session_name}\x1f#{window_index}"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestTmuxFormatParse(t *testing.T) {
+	f := tmuxFormat{"session_name", "pane_title", "pane_current_path"}
+	line := func(values ...string) string {
+		return strings.Join(values, formatDelimiter)
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		want    []tmuxRecord
+		wantErr bool
+	}{
+		{
+			name:   "spaces",
+			output: line("my work", "a title", "/home/me/my project") + "\n",
+			want:   []tmuxRecord{{"session_name": "my work", "pane_title": "a title", "pane_current_path": "/home/me/my project"}},
+		},
+		{
+			name:   "unicode",
+			output: line("zażółć", "🚀 build", "/tmp/日本") + "\n",
+			want:   []tmuxRecord{{"session_name": "zażółć", "pane_title": "🚀 build", "pane_current_path": "/tmp/日本"}},
+		},
+		{
+			name:   "colons",
+			output: line("work", "host:8080", "/a:b") + "\n",
+			want:   []tmuxRecord{{"session_name": "work", "pane_title": "host:8080", "pane_current_path": "/a:b"}},
+		},
+		{
+			name:   "empty title",
+			output: line("work", "", "/tmp") + "\n",
+			want:   []tmuxRecord{{"session_name": "work", "pane_title": "", "pane_current_path": "/tmp"}},
+		},
+		{
+			name:   "multiple lines",
+			output: line("a", "1", "/a") + "\n" + line("b", "2", "/b") + "\n",
+			want: []tmuxRecord{
+				{"session_name": "a", "pane_title": "1", "pane_current_path": "/a"},
+				{"session_name": "b", "pane_title": "2", "pane_current_path": "/b"},
+			},
+		},
+		{
+			name:   "no trailing newline",
+			output: line("work", "t", "/tmp"),
+			want:   []tmuxRecord{{"session_name": "work", "pane_title": "t", "pane_current_path": "/tmp"}},
+		},
+		{
+			name:   "empty lines",
+			output: "\n" + line("work", "t", "/tmp") + "\n\n",
+			want:   []tmuxRecord{{"session_name": "work", "pane_title": "t", "pane_current_path": "/tmp"}},
+		},
+		{
+			name:   "empty output",
+			output: "",
+			want:   nil,
+		},
+		{
+			name:    "missing field",
+			output:  line("work", "/tmp") + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "delimiter in value",
+			output:  line("work", "a"+formatDelimiter+"b", "/tmp") + "\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.parse([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTmuxRecordInt(t *testing.T) {
+	r := tmuxRecord{"window_index": "3", "window_name": "code"}
+
+	if v, err := r.int("window_index"); err != nil || v != 3 {
+		t.Errorf("int(window_index) = %d, %v, want 3", v, err)
+	}
+
+	if _, err := r.int("window_name"); err == nil {
+		t.Error("int(window_name) error = nil, want error")
+	}
+}
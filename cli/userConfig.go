@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const userConfigFile = "config"
+
+// userConfigExtensions lists extensions of the config file in order of lookup.
+var userConfigExtensions = []string{".toml", ".yaml", ".yml", ".json"}
+
+// userConfig holds defaults read from config file in the config dir. Flags
+// of commands always override them.
+type userConfig struct {
+	// DefaultTemplate is used by new-session when -templ is not set.
+	DefaultTemplate string `json:"defaultTemplate"`
+	// SkipConfirm answers yes to every confirmation prompt.
+	SkipConfirm bool `json:"skipConfirm"`
+	// OnConflict is the default of restore-sessions -on-conflict.
+	OnConflict string `json:"onConflict"`
+	// AutosaveInterval is the default of autosave -interval in minutes.
+	AutosaveInterval int `json:"autosaveInterval"`
+	// tmuxServer is the default server, set with socketName or socketPath.
+	tmuxServer
+	Menu  menuConfig  `json:"menu"`
+	Theme themeConfig `json:"theme"`
+}
+
+// menuConfig holds keys of the interactive menu on top of arrows and Enter.
+type menuConfig struct {
+	Up   string `json:"up"`
+	Down string `json:"down"`
+	Quit string `json:"quit"`
+}
+
+// themeConfig holds colors of the interactive menu. Empty color keeps the
+// default color of the terminal.
+type themeConfig struct {
+	Selected string `json:"selected"`
+	Desc     string `json:"desc"`
+	Border   string `json:"border"`
+}
+
+var themeColors = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+}
+
+func defaultUserConfig() userConfig {
+	return userConfig{
+		OnConflict:       string(conflictSkip),
+		AutosaveInterval: defaultAutosaveInterval,
+		Menu: menuConfig{
+			Up:   "k",
+			Down: "j",
+			Quit: "q",
+		},
+	}
+}
+
+// cfg is the configuration in use, loaded by NewCli.
+var cfg = defaultUserConfig()
+
+// loadUserConfig reads config file from the config dir into cfg. Missing
+// file keeps the defaults.
+func loadUserConfig() error {
+	path, err := getConfigDirPath()
+	if err != nil {
+		return err
+	}
+
+	for _, ext := range userConfigExtensions {
+		filePath := filepath.Join(path, userConfigFile+ext)
+
+		out, err := os.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("Unable to read config file at path: %s \n", filePath)
+		}
+
+		format, _ := formatFromPath(filePath)
+		c := defaultUserConfig()
+		if err := decodeFile(out, format, &c); err != nil {
+			return fmt.Errorf("Cannot unmarshal config file: %s \n", filePath)
+		}
+
+		if err := c.validate(); err != nil {
+			return fmt.Errorf("Invalid config file: %s. %s", filePath, err)
+		}
+
+		cfg = c
+		return nil
+	}
+
+	return nil
+}
+
+func (c userConfig) validate() error {
+	if _, err := parseConflictStrategy(c.OnConflict); err != nil {
+		return err
+	}
+
+	if c.AutosaveInterval <= 0 {
+		return fmt.Errorf("Autosave interval must be positive \n")
+	}
+
+	for _, key := range []string{c.Menu.Up, c.Menu.Down, c.Menu.Quit} {
+		if len(key) > 1 {
+			return fmt.Errorf("Menu key must be a single character: %s \n", key)
+		}
+	}
+
+	for _, color := range []string{c.Theme.Selected, c.Theme.Desc, c.Theme.Border} {
+		if _, ok := themeColors[color]; color != "" && !ok {
+			return fmt.Errorf("Unknown color: %s \n", color)
+		}
+	}
+
+	return nil
+}
+
+// colorize wraps s in color of the theme. Colors are left out when NO_COLOR
+// is set.
+func colorize(s, color string) string {
+	code, ok := themeColors[color]
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return s
+	}
+
+	return "\033[" + code + "m" + s + "\033[0m"
+}
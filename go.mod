@@ -2,6 +2,10 @@ module github.com/rogemus/tmxu
 
 go 1.26
 
-require golang.org/x/term v0.39.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/term v0.39.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require golang.org/x/sys v0.40.0 // indirect